@@ -0,0 +1,98 @@
+// Package cborpb encodes gen-platform messages as CBOR for the facades'
+// MarshalCBOR/UnmarshalCBOR methods.
+//
+// A message is written as its pbtree value tree: a CBOR map keyed by the
+// fields' JSON names, with bytes fields as CBOR byte strings rather than
+// base64 text. Encoding uses the deterministic Core rules of RFC 8949 so the
+// output can be signed.
+package cborpb
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tinywideclouds/go-platform/internal/pbtree"
+)
+
+var (
+	encMode cbor.EncMode
+	decMode cbor.DecMode
+)
+
+func init() {
+	var err error
+	if encMode, err = cbor.CoreDetEncOptions().EncMode(); err != nil {
+		panic(err)
+	}
+	decOpts := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}
+	if decMode, err = decOpts.DecMode(); err != nil {
+		panic(err)
+	}
+}
+
+// Marshal encodes v with the deterministic encoding used by this package.
+func Marshal(v any) ([]byte, error) {
+	return encMode.Marshal(v)
+}
+
+// Unmarshal decodes data into v, with maps decoded as map[string]any.
+func Unmarshal(data []byte, v any) error {
+	return decMode.Unmarshal(data, v)
+}
+
+// MarshalMessage encodes m as a CBOR map.
+func MarshalMessage(m proto.Message) ([]byte, error) {
+	v, err := pbtree.FromMessage(m.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(v)
+}
+
+// UnmarshalMessage decodes a CBOR map produced by MarshalMessage into m,
+// which is reset first.
+func UnmarshalMessage(data []byte, m proto.Message) error {
+	var v map[string]any
+	if err := Unmarshal(data, &v); err != nil {
+		return err
+	}
+	proto.Reset(m)
+	return pbtree.ToMessage(v, m.ProtoReflect())
+}
+
+// MarshalMessageExt is MarshalMessage with the fields of ext, a struct with
+// cbor (or json) tags, added to the map. It carries native fields the message
+// does not have yet.
+func MarshalMessageExt(m proto.Message, ext any) ([]byte, error) {
+	v, err := pbtree.FromMessage(m.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	extData, err := Marshal(ext)
+	if err != nil {
+		return nil, err
+	}
+	var extFields map[string]any
+	if err := Unmarshal(extData, &extFields); err != nil {
+		return nil, err
+	}
+	for k, f := range extFields {
+		v[k] = f
+	}
+	return Marshal(v)
+}
+
+// UnmarshalMessageExt is UnmarshalMessage that also decodes the map into ext.
+func UnmarshalMessageExt(data []byte, m proto.Message, ext any) error {
+	if err := UnmarshalMessage(data, m); err != nil {
+		return err
+	}
+	return Unmarshal(data, ext)
+}
+
+// IsNull reports whether data is the CBOR null or undefined value.
+func IsNull(data []byte) bool {
+	return len(data) == 1 && (data[0] == 0xf6 || data[0] == 0xf7)
+}
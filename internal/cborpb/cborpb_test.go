@@ -0,0 +1,67 @@
+package cborpb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	routingv1 "github.com/tinywideclouds/gen-platform/go/types/routing/v1"
+	smv1 "github.com/tinywideclouds/gen-platform/go/types/secure/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMessage_RoundTrip(t *testing.T) {
+	original := &routingv1.QueuedMessagePb{
+		Id: "msg-1",
+		Envelope: &smv1.SecureEnvelopePb{
+			RecipientId:   "urn:sm:user:bob",
+			EncryptedData: []byte{0, 1, 255},
+			Priority:      proto.Int32(-2),
+		},
+	}
+	data, err := MarshalMessage(original)
+	require.NoError(t, err)
+
+	var got routingv1.QueuedMessagePb
+	require.NoError(t, UnmarshalMessage(data, &got))
+	assert.True(t, proto.Equal(original, &got), "got %v", &got)
+}
+
+func TestMarshalMessage_Wire(t *testing.T) {
+	data, err := MarshalMessage(&smv1.SecureEnvelopePb{RecipientId: "u", Signature: []byte{9}})
+	require.NoError(t, err)
+	// map(2) { "signature": bytes(1) 09, "recipientId": text "u" }, keys in
+	// Core deterministic (length-first) order
+	want := []byte{0xa2,
+		0x69, 's', 'i', 'g', 'n', 'a', 't', 'u', 'r', 'e', 0x41, 0x09,
+		0x6b, 'r', 'e', 'c', 'i', 'p', 'i', 'e', 'n', 't', 'I', 'd', 0x61, 'u',
+	}
+	assert.Equal(t, want, data)
+}
+
+func TestIsNull(t *testing.T) {
+	assert.True(t, IsNull([]byte{0xf6}))
+	assert.True(t, IsNull([]byte{0xf7}))
+	assert.False(t, IsNull([]byte{0xa0}))
+}
+
+type testExt struct {
+	AssociatedData []byte `cbor:"associatedData,omitempty"`
+	Note           string `cbor:"note,omitempty"`
+}
+
+func TestMessageExt_RoundTrip(t *testing.T) {
+	pb := &smv1.SecureEnvelopePb{RecipientId: "urn:sm:user:bob"}
+	data, err := MarshalMessageExt(pb, testExt{AssociatedData: []byte{1}})
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, Unmarshal(data, &raw))
+	assert.Equal(t, map[string]any{"recipientId": "urn:sm:user:bob", "associatedData": []byte{1}}, raw)
+
+	var got smv1.SecureEnvelopePb
+	var ext testExt
+	require.NoError(t, UnmarshalMessageExt(data, &got, &ext))
+	assert.True(t, proto.Equal(pb, &got))
+	assert.Equal(t, testExt{AssociatedData: []byte{1}}, ext)
+}
@@ -0,0 +1,88 @@
+// Package convert holds the helpers shared by the v1 facade packages: the
+// protojson options every facade marshals with, and generic slice conversion
+// for the ListToProto/ListFromProto functions.
+package convert
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// MarshalOptions returns the options every facade marshals with: camelCase
+// (json_name) keys and unpopulated fields omitted. Each call returns a fresh
+// value, so a caller adjusting its copy (e.g. for MarshalJSONWith) cannot
+// change how anything else marshals.
+func MarshalOptions() protojson.MarshalOptions {
+	return protojson.MarshalOptions{
+		UseProtoNames:   false,
+		EmitUnpopulated: false,
+	}
+}
+
+// UnmarshalOptions returns the options every facade unmarshals with: unknown
+// fields are discarded for forward compatibility. Like MarshalOptions, each
+// call returns a fresh value.
+func UnmarshalOptions() protojson.UnmarshalOptions {
+	return protojson.UnmarshalOptions{
+		DiscardUnknown: true,
+	}
+}
+
+// List applies fn to every element of items. A nil slice maps to nil and an
+// empty slice to an empty one.
+func List[N, P any](items []N, fn func(N) P) []P {
+	if items == nil {
+		return nil
+	}
+	out := make([]P, len(items))
+	for i, item := range items {
+		out[i] = fn(item)
+	}
+	return out
+}
+
+// ListErr is List for fallible conversions. It stops at the first error and
+// returns it wrapped with the index of the failing element.
+func ListErr[P, N any](items []P, fn func(P) (N, error)) ([]N, error) {
+	if items == nil {
+		return nil, nil
+	}
+	out := make([]N, len(items))
+	for i, item := range items {
+		v, err := fn(item)
+		if err != nil {
+			return nil, fmt.Errorf("at index %d: %w", i, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// ctxCheckInterval is how many elements ListErrContext converts between
+// checks of the context.
+const ctxCheckInterval = 64
+
+// ListErrContext is ListErr that also checks ctx before the first element and
+// every ctxCheckInterval elements after it. When the context is done it stops
+// and returns ctx.Err() unwrapped.
+func ListErrContext[P, N any](ctx context.Context, items []P, fn func(P) (N, error)) ([]N, error) {
+	if items == nil {
+		return nil, ctx.Err()
+	}
+	out := make([]N, len(items))
+	for i, item := range items {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := fn(item)
+		if err != nil {
+			return nil, fmt.Errorf("at index %d: %w", i, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
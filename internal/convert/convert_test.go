@@ -0,0 +1,133 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestList(t *testing.T) {
+	assert.Nil(t, List[int, string](nil, strconv.Itoa))
+
+	empty := List([]int{}, strconv.Itoa)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+
+	assert.Equal(t, []string{"1", "2", "3"}, List([]int{1, 2, 3}, strconv.Itoa))
+}
+
+func TestListErr(t *testing.T) {
+	t.Run("Nil and empty", func(t *testing.T) {
+		out, err := ListErr[string, int](nil, strconv.Atoi)
+		require.NoError(t, err)
+		assert.Nil(t, out)
+
+		out, err = ListErr([]string{}, strconv.Atoi)
+		require.NoError(t, err)
+		assert.NotNil(t, out)
+		assert.Empty(t, out)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		out, err := ListErr([]string{"1", "2"}, strconv.Atoi)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, out)
+	})
+
+	t.Run("Error carries the index", func(t *testing.T) {
+		sentinel := errors.New("bad item")
+		calls := 0
+		out, err := ListErr([]string{"ok", "bad", "never"}, func(s string) (string, error) {
+			calls++
+			if s == "bad" {
+				return "", sentinel
+			}
+			return s, nil
+		})
+		require.Error(t, err)
+		assert.Nil(t, out)
+		assert.ErrorIs(t, err, sentinel)
+		assert.Equal(t, "at index 1: bad item", err.Error())
+		assert.Equal(t, 2, calls, "conversion should stop at the first error")
+	})
+}
+
+// cancelAfter is a context whose Err reports context.Canceled once it has
+// been called more than n times.
+type cancelAfter struct {
+	context.Context
+	n, calls int
+}
+
+func (c *cancelAfter) Err() error {
+	c.calls++
+	if c.calls > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestListErrContext(t *testing.T) {
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	t.Run("Completes", func(t *testing.T) {
+		out, err := ListErrContext(context.Background(), items, strconv.Atoi)
+		require.NoError(t, err)
+		assert.Len(t, out, 1000)
+		assert.Equal(t, 999, out[999])
+	})
+
+	t.Run("Cancelled mid-conversion", func(t *testing.T) {
+		ctx := &cancelAfter{Context: context.Background(), n: 2}
+		converted := 0
+		out, err := ListErrContext(ctx, items, func(s string) (int, error) {
+			converted++
+			return strconv.Atoi(s)
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, out)
+		assert.Equal(t, 2*ctxCheckInterval, converted)
+	})
+
+	t.Run("Already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := ListErrContext(ctx, items, strconv.Atoi)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = ListErrContext[string, int](ctx, nil, strconv.Atoi)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestOptions_FreshPerCall(t *testing.T) {
+	msg := &apipb.Method{Name: "Get", RequestTypeUrl: "type.example.com/Req"}
+	before, err := MarshalOptions().Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(before), `"requestTypeUrl"`)
+	assert.NotContains(t, string(before), `"responseTypeUrl"`)
+
+	// A caller adjusting its copy, e.g. for MarshalJSONWith
+	opts := MarshalOptions()
+	opts.UseProtoNames = true
+	opts.EmitUnpopulated = true
+	adjusted, err := opts.Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(adjusted), `"request_type_url"`)
+
+	after, err := MarshalOptions().Marshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+
+	unmarshal := UnmarshalOptions()
+	unmarshal.DiscardUnknown = false
+	require.Error(t, unmarshal.Unmarshal([]byte(`{"future":1}`), &apipb.Method{}))
+	assert.NoError(t, UnmarshalOptions().Unmarshal([]byte(`{"future":1}`), &apipb.Method{}))
+}
@@ -0,0 +1,335 @@
+// Package jsonext carries native-only fields through the protojson facades.
+//
+// Some native structs have fields their gen-platform message does not have
+// yet. Their MarshalJSON produces the protojson object as usual and then uses
+// Merge to append the extra fields, encoded with encoding/json. On the way
+// back in, protojson discards the unknown members and the same extension
+// struct is decoded from the original bytes with json.Unmarshal.
+//
+// protojson has no way to keep unknown JSON members, so a facade that must
+// forward members added by a newer producer collects them with Unknown and
+// merges them back on the way out.
+package jsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Merge appends the members of ext, encoded with encoding/json, to the JSON
+// object obj. Empty objects on either side are handled, so an extension whose
+// fields are all omitted leaves obj unchanged.
+func Merge(obj []byte, ext any) ([]byte, error) {
+	extJSON, err := json.Marshal(ext)
+	if err != nil {
+		return nil, err
+	}
+	return mergeObjects(obj, extJSON)
+}
+
+// MergeWith is Merge for an extension struct written next to protojson
+// output made with opts, so the extension members follow the same options:
+// UseProtoNames writes each member under its proto name (see ProtoName), and
+// EmitUnpopulated writes the empty fields omitempty would drop, the way
+// protojson writes them: "" for bytes, [] and {} for slices and maps, and
+// null for raw JSON. With Multiline or Indent the extension members are
+// indented to match. ext must be a struct.
+func MergeWith(obj []byte, ext any, opts protojson.MarshalOptions) ([]byte, error) {
+	extJSON, err := marshalExt(ext, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Multiline || opts.Indent != "" {
+		indent := opts.Indent
+		if indent == "" {
+			indent = "  "
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, extJSON, "", indent); err != nil {
+			return nil, err
+		}
+		extJSON = buf.Bytes()
+	}
+	return mergeObjects(obj, extJSON)
+}
+
+// marshalExt encodes the extension struct ext as a JSON object following
+// the UseProtoNames and EmitUnpopulated options.
+func marshalExt(ext any, opts protojson.MarshalOptions) ([]byte, error) {
+	if !opts.UseProtoNames && !opts.EmitUnpopulated {
+		return json.Marshal(ext)
+	}
+	v := reflect.ValueOf(ext)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := range v.NumField() {
+		name, omitEmpty, ok := jsonTag(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if omitEmpty && field.IsZero() && !opts.EmitUnpopulated {
+			continue
+		}
+		if opts.UseProtoNames {
+			name = ProtoName(name)
+		}
+		value, err := marshalField(field)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalField encodes one extension field, writing an empty slice, map or
+// raw JSON value as protojson writes an unpopulated field rather than as
+// null.
+func marshalField(field reflect.Value) ([]byte, error) {
+	if field.Type() == reflect.TypeFor[json.RawMessage]() {
+		if field.Len() == 0 {
+			return []byte("null"), nil
+		}
+		return field.Bytes(), nil
+	}
+	if field.IsZero() {
+		switch {
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+			return []byte(`""`), nil
+		case field.Kind() == reflect.Slice:
+			return []byte("[]"), nil
+		case field.Kind() == reflect.Map:
+			return []byte("{}"), nil
+		}
+	}
+	return json.Marshal(field.Interface())
+}
+
+// Unmarshal decodes the JSON object data into the extension struct ext
+// points to, accepting each member under its json tag name or its proto
+// name, as protojson does for proto fields. The json tag name wins when both
+// are present.
+func Unmarshal(data []byte, ext any) error {
+	if err := json.Unmarshal(data, ext); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	v := reflect.ValueOf(ext).Elem()
+	for i := range v.NumField() {
+		name, _, ok := jsonTag(v.Type().Field(i))
+		if !ok || ProtoName(name) == name {
+			continue
+		}
+		if members == nil {
+			if err := json.Unmarshal(data, &members); err != nil {
+				return err
+			}
+		}
+		if _, ok := members[name]; ok {
+			continue
+		}
+		if value, ok := members[ProtoName(name)]; ok {
+			if err := json.Unmarshal(value, v.Field(i).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalList writes the JSON object {name: [...]} for a list facade whose
+// elements have a MarshalJSONWith, passing opts to each element; a nil
+// element is written as null. An empty list is omitted, or written as []
+// with EmitUnpopulated. name is the same in either naming, so it is not
+// converted.
+func MarshalList[E any, P interface {
+	*E
+	MarshalJSONWith(protojson.MarshalOptions) ([]byte, error)
+}](name string, items []P, opts protojson.MarshalOptions) ([]byte, error) {
+	if len(items) == 0 && !opts.EmitUnpopulated {
+		return []byte("{}"), nil
+	}
+	elems := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		if item == nil {
+			elems[i] = json.RawMessage("null")
+			continue
+		}
+		data, err := item.MarshalJSONWith(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s element at index %d: %w", name, i, err)
+		}
+		elems[i] = data
+	}
+	return json.Marshal(map[string][]json.RawMessage{name: elems})
+}
+
+// ProtoName returns the snake_case proto name for the lowerCamelCase JSON
+// name of an extension member, e.g. "enc_algorithm" for "encAlgorithm".
+func ProtoName(jsonName string) string {
+	var b strings.Builder
+	for _, r := range jsonName {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('_')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// jsonTag returns the member name and omitempty flag from f's json tag, and
+// false for a field encoding/json skips.
+func jsonTag(f reflect.StructField) (name string, omitEmpty, ok bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false, false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, slices.Contains(strings.Split(opts, ","), "omitempty"), true
+}
+
+// mergeObjects appends the members of the JSON object extJSON to obj.
+func mergeObjects(obj, extJSON []byte) ([]byte, error) {
+	obj = bytes.TrimSpace(obj)
+	if !isObject(obj) || !isObject(extJSON) {
+		return nil, fmt.Errorf("jsonext: cannot merge %s into %s", extJSON, obj)
+	}
+	if isEmptyObject(extJSON) {
+		return obj, nil
+	}
+	if isEmptyObject(obj) {
+		return extJSON, nil
+	}
+
+	out := make([]byte, 0, len(obj)+len(extJSON))
+	out = append(out, bytes.TrimRight(obj[:len(obj)-1], " \t\r\n")...)
+	out = append(out, ',')
+	return append(out, extJSON[1:]...), nil
+}
+
+// Canonical re-encodes the JSON document data with object keys sorted, no
+// insignificant whitespace and HTML characters left unescaped. Numbers are
+// copied as written. The result is byte-stable for equal input values, which
+// protojson output deliberately is not.
+func Canonical(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// KnownMembers returns the member names a facade reads: both the JSON and
+// the proto name of every field of desc, since protojson accepts either, and
+// of every member of each extension struct in exts, as Unmarshal does.
+func KnownMembers(desc protoreflect.MessageDescriptor, exts ...any) map[string]bool {
+	known := map[string]bool{}
+	fields := desc.Fields()
+	for i := range fields.Len() {
+		known[fields.Get(i).JSONName()] = true
+		known[string(fields.Get(i).Name())] = true
+	}
+	for _, ext := range exts {
+		t := reflect.TypeOf(ext)
+		for i := range t.NumField() {
+			if name, _, ok := jsonTag(t.Field(i)); ok {
+				known[name] = true
+				known[ProtoName(name)] = true
+			}
+		}
+	}
+	return known
+}
+
+// Unknown returns the members of the JSON object data whose names are not in
+// known, with their values as written, or nil if there are none. Pass the
+// result to Merge to write them back out.
+func Unknown(data []byte, known map[string]bool) (map[string]json.RawMessage, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	var unknown map[string]json.RawMessage
+	for name, value := range members {
+		if known[name] {
+			continue
+		}
+		if unknown == nil {
+			unknown = map[string]json.RawMessage{}
+		}
+		unknown[name] = value
+	}
+	return unknown, nil
+}
+
+// Remove deletes the top-level member name from the JSON object obj, together
+// with its separating comma, and leaves the rest of obj as written, so
+// Multiline and Indent output keeps its layout. obj is returned unchanged if
+// it has no such member.
+func Remove(obj []byte, name string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(obj))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("jsonext: cannot remove %q from %s", name, obj)
+	}
+	prevEnd := dec.InputOffset()
+	for first := true; dec.More(); first = false {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		end := dec.InputOffset()
+		if tok != name {
+			prevEnd = end
+			continue
+		}
+		if !first || !dec.More() {
+			// Drop the leading comma, or the only member.
+			return slices.Delete(slices.Clone(obj), int(prevEnd), int(end)), nil
+		}
+		// The first of several members: drop it and the comma after it.
+		comma := end + int64(bytes.IndexByte(obj[end:], ','))
+		return slices.Delete(slices.Clone(obj), int(prevEnd), int(comma)+1), nil
+	}
+	return obj, nil
+}
+
+func isObject(b []byte) bool {
+	return len(b) >= 2 && b[0] == '{' && b[len(b)-1] == '}'
+}
+
+func isEmptyObject(b []byte) bool {
+	return len(bytes.TrimSpace(b[1:len(b)-1])) == 0
+}
@@ -0,0 +1,195 @@
+package jsonext
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type testExt struct {
+	Phone string `json:"phone,omitempty"`
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("Appends populated fields", func(t *testing.T) {
+		out, err := Merge([]byte(`{"name":"Bob"}`), testExt{Phone: "+441234"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"Bob","phone":"+441234"}`, string(out))
+	})
+
+	t.Run("Empty extension leaves object unchanged", func(t *testing.T) {
+		out, err := Merge([]byte(`{"name":"Bob"}`), testExt{})
+		require.NoError(t, err)
+		assert.Equal(t, `{"name":"Bob"}`, string(out))
+	})
+
+	t.Run("Empty object", func(t *testing.T) {
+		out, err := Merge([]byte(`{ }`), testExt{Phone: "+441234"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"phone":"+441234"}`, string(out))
+	})
+
+	t.Run("Rejects non-objects", func(t *testing.T) {
+		_, err := Merge([]byte(`null`), testExt{Phone: "+441234"})
+		assert.Error(t, err)
+	})
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"Last member", `{"name":"Bob","id":""}`, `{"name":"Bob"}`},
+		{"First member", `{"id":"", "name":"Bob"}`, `{ "name":"Bob"}`},
+		{"Middle member", `{"a":1,"id":{"x":[1,2]},"b":2}`, `{"a":1,"b":2}`},
+		{"Only member", `{"id":""}`, `{}`},
+		{"Missing", `{"name":"Bob"}`, `{"name":"Bob"}`},
+		{"Nested is kept", `{"a":{"id":1}}`, `{"a":{"id":1}}`},
+		{"Multiline", "{\n  \"id\": \"\",\n  \"name\": \"Bob\"\n}", "{\n  \"name\": \"Bob\"\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Remove([]byte(tt.in), "id")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(out))
+		})
+	}
+
+	_, err := Remove([]byte(`null`), "id")
+	assert.Error(t, err)
+}
+
+func TestCanonical(t *testing.T) {
+	out, err := Canonical([]byte(`{ "b": {"z": 1, "a": [3, 1.50]},  "a": "<x>" }`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"<x>","b":{"a":[3,1.50],"z":1}}`, string(out))
+
+	_, err = Canonical([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestKnownMembers(t *testing.T) {
+	known := KnownMembers((&emptypb.Empty{}).ProtoReflect().Descriptor(), testExt{})
+	assert.Equal(t, map[string]bool{"phone": true}, known)
+
+	known = KnownMembers((&fieldmaskpb.FieldMask{}).ProtoReflect().Descriptor())
+	assert.Equal(t, map[string]bool{"paths": true}, known)
+}
+
+func TestUnknown(t *testing.T) {
+	known := map[string]bool{"name": true, "phone": true}
+
+	unknown, err := Unknown([]byte(`{"name":"Bob","future":{"a":[1, 2]},"flag":true}`), known)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"future": json.RawMessage(`{"a":[1, 2]}`),
+		"flag":   json.RawMessage(`true`),
+	}, unknown)
+
+	merged, err := Merge([]byte(`{"name":"Bob"}`), unknown)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Bob","future":{"a":[1,2]},"flag":true}`, string(merged))
+
+	unknown, err = Unknown([]byte(`{"name":"Bob"}`), known)
+	require.NoError(t, err)
+	assert.Nil(t, unknown)
+
+	_, err = Unknown([]byte(`[1]`), known)
+	assert.Error(t, err)
+}
+
+type optionsExt struct {
+	KeyID   string          `json:"keyId,omitempty"`
+	Data    []byte          `json:"data,omitempty"`
+	Tags    []string        `json:"tags,omitempty"`
+	Nested  json.RawMessage `json:"nested,omitempty"`
+	Count   int             `json:"count"`
+	private int
+}
+
+func TestMergeWith(t *testing.T) {
+	ext := optionsExt{KeyID: "k1"}
+
+	t.Run("Default options match Merge", func(t *testing.T) {
+		want, err := Merge([]byte(`{"a":1}`), ext)
+		require.NoError(t, err)
+		got, err := MergeWith([]byte(`{"a":1}`), ext, protojson.MarshalOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	})
+
+	t.Run("Proto names", func(t *testing.T) {
+		out, err := MergeWith([]byte(`{"a":1}`), ext, protojson.MarshalOptions{UseProtoNames: true})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":1,"key_id":"k1","count":0}`, string(out))
+	})
+
+	t.Run("Emit unpopulated", func(t *testing.T) {
+		out, err := MergeWith([]byte(`{}`), optionsExt{}, protojson.MarshalOptions{EmitUnpopulated: true})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"keyId":"","data":"","tags":[],"nested":null,"count":0}`, string(out))
+	})
+
+	t.Run("Multiline", func(t *testing.T) {
+		out, err := MergeWith([]byte("{\n  \"a\": 1\n}"), ext, protojson.MarshalOptions{Multiline: true})
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"a\": 1,\n  \"keyId\": \"k1\",\n  \"count\": 0\n}", string(out))
+
+		out, err = MergeWith([]byte("{\n\t\"a\": 1\n}"), ext, protojson.MarshalOptions{Indent: "\t"})
+		require.NoError(t, err)
+		assert.Equal(t, "{\n\t\"a\": 1,\n\t\"keyId\": \"k1\",\n\t\"count\": 0\n}", string(out))
+	})
+}
+
+func TestUnmarshal(t *testing.T) {
+	var ext optionsExt
+	require.NoError(t, Unmarshal([]byte(`{"key_id":"k1","data":"AQ==","count":2}`), &ext))
+	assert.Equal(t, optionsExt{KeyID: "k1", Data: []byte{1}, Count: 2}, ext)
+
+	ext = optionsExt{}
+	require.NoError(t, Unmarshal([]byte(`{"key_id":"snake","keyId":"camel"}`), &ext))
+	assert.Equal(t, "camel", ext.KeyID)
+
+	assert.Error(t, Unmarshal([]byte(`{"key_id":5}`), &ext))
+}
+
+func TestProtoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"keyId":         "key_id",
+		"encAlgorithm":  "enc_algorithm",
+		"schemaVersion": "schema_version",
+		"phone":         "phone",
+	} {
+		assert.Equal(t, want, ProtoName(in), in)
+	}
+}
+
+type listItem struct{ N int }
+
+func (l listItem) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	if opts.UseProtoNames {
+		return json.Marshal(map[string]int{"n_value": l.N})
+	}
+	return json.Marshal(map[string]int{"nValue": l.N})
+}
+
+func TestMarshalList(t *testing.T) {
+	out, err := MarshalList("items", []*listItem{{N: 1}, nil}, protojson.MarshalOptions{UseProtoNames: true})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[{"n_value":1},null]}`, string(out))
+
+	out, err = MarshalList("items", []*listItem(nil), protojson.MarshalOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(out))
+
+	out, err = MarshalList("items", []*listItem(nil), protojson.MarshalOptions{EmitUnpopulated: true})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[]}`, string(out))
+}
@@ -0,0 +1,83 @@
+// Package msgpackpb encodes gen-platform messages as msgpack for the facades'
+// MarshalMsgpack/UnmarshalMsgpack methods.
+//
+// A message is written as its pbtree value tree: a msgpack map keyed by the
+// fields' JSON names, with bytes fields as msgpack bin values rather than
+// base64 strings, which is the point of using msgpack.
+package msgpackpb
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tinywideclouds/go-platform/internal/pbtree"
+)
+
+// Marshal encodes m as a msgpack map. Keys are written in sorted order so the
+// output is deterministic.
+func Marshal(m proto.Message) ([]byte, error) {
+	v, err := pbtree.FromMessage(m.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	return marshalSorted(v)
+}
+
+// Unmarshal decodes a msgpack map produced by Marshal into m, which is reset
+// first.
+func Unmarshal(data []byte, m proto.Message) error {
+	var v map[string]any
+	if err := msgpack.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	proto.Reset(m)
+	return pbtree.ToMessage(v, m.ProtoReflect())
+}
+
+// MarshalExt is Marshal with the fields of ext, a struct with msgpack tags,
+// added to the map. It carries native fields the message does not have yet.
+func MarshalExt(m proto.Message, ext any) ([]byte, error) {
+	v, err := pbtree.FromMessage(m.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	extData, err := msgpack.Marshal(ext)
+	if err != nil {
+		return nil, err
+	}
+	var extFields map[string]any
+	if err := msgpack.Unmarshal(extData, &extFields); err != nil {
+		return nil, err
+	}
+	for k, f := range extFields {
+		v[k] = f
+	}
+	return marshalSorted(v)
+}
+
+// UnmarshalExt is Unmarshal that also decodes the map into ext.
+func UnmarshalExt(data []byte, m proto.Message, ext any) error {
+	if err := Unmarshal(data, m); err != nil {
+		return err
+	}
+	return msgpack.Unmarshal(data, ext)
+}
+
+func marshalSorted(v any) ([]byte, error) {
+	enc := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(enc)
+	var buf bytesBuffer
+	enc.Reset(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+type bytesBuffer []byte
+
+func (b *bytesBuffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
@@ -0,0 +1,112 @@
+package msgpackpb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	nv1 "github.com/tinywideclouds/gen-platform/go/types/notification/v1"
+	routingv1 "github.com/tinywideclouds/gen-platform/go/types/routing/v1"
+	smv1 "github.com/tinywideclouds/gen-platform/go/types/secure/v1"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		out  proto.Message
+	}{
+		{
+			name: "Nested message with bytes and optional int",
+			msg: &routingv1.QueuedMessagePb{
+				Id: "msg-1",
+				Envelope: &smv1.SecureEnvelopePb{
+					RecipientId:   "urn:sm:user:bob",
+					EncryptedData: []byte{0, 1, 2, 255},
+					IsEphemeral:   true,
+					Priority:      proto.Int32(-3),
+				},
+			},
+			out: &routingv1.QueuedMessagePb{},
+		},
+		{
+			name: "Repeated messages",
+			msg: &smv1.SecureEnvelopeListPb{Envelopes: []*smv1.SecureEnvelopePb{
+				{RecipientId: "urn:sm:user:a"},
+				{RecipientId: "urn:sm:user:b", Signature: []byte{9}},
+			}},
+			out: &smv1.SecureEnvelopeListPb{},
+		},
+		{
+			name: "String map",
+			msg: &nv1.NotificationRequestPb{
+				RecipientId: "urn:sm:user:bob",
+				Content:     &nv1.NotificationRequestPb_Content{Title: "hi"},
+				DataPayload: map[string]string{"a": "1", "b": "2"},
+			},
+			out: &nv1.NotificationRequestPb{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Marshal(tc.msg)
+			require.NoError(t, err)
+			require.NoError(t, Unmarshal(data, tc.out))
+			assert.True(t, proto.Equal(tc.msg, tc.out), "got %v", tc.out)
+		})
+	}
+}
+
+func TestMarshal_Wire(t *testing.T) {
+	data, err := Marshal(&smv1.SecureEnvelopePb{RecipientId: "urn:sm:user:bob", EncryptedData: []byte{1, 2}})
+	require.NoError(t, err)
+
+	var m map[string]any
+	require.NoError(t, msgpack.Unmarshal(data, &m))
+	assert.Equal(t, map[string]any{
+		"recipientId":   "urn:sm:user:bob",
+		"encryptedData": []byte{1, 2},
+	}, m, "keys are JSON names and bytes stay raw")
+
+	again, err := Marshal(&smv1.SecureEnvelopePb{RecipientId: "urn:sm:user:bob", EncryptedData: []byte{1, 2}})
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	data, err := msgpack.Marshal(map[string]any{"encryptedData": "AQID"})
+	require.NoError(t, err)
+	err = Unmarshal(data, &smv1.SecureEnvelopePb{})
+	assert.ErrorContains(t, err, "encryptedData")
+
+	data, err = msgpack.Marshal(map[string]any{"unknown": 1, "recipientId": "x"})
+	require.NoError(t, err)
+	var pb smv1.SecureEnvelopePb
+	require.NoError(t, Unmarshal(data, &pb))
+	assert.Equal(t, "x", pb.RecipientId)
+
+	assert.Error(t, Unmarshal([]byte{0xc1}, &pb))
+}
+
+type testExt struct {
+	AssociatedData []byte `msgpack:"associatedData,omitempty"`
+	Note           string `msgpack:"note,omitempty"`
+}
+
+func TestExt_RoundTrip(t *testing.T) {
+	pb := &smv1.SecureEnvelopePb{RecipientId: "urn:sm:user:bob"}
+	data, err := MarshalExt(pb, testExt{AssociatedData: []byte{1}})
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, msgpack.Unmarshal(data, &raw))
+	assert.Equal(t, map[string]any{"recipientId": "urn:sm:user:bob", "associatedData": []byte{1}}, raw)
+
+	var got smv1.SecureEnvelopePb
+	var ext testExt
+	require.NoError(t, UnmarshalExt(data, &got, &ext))
+	assert.True(t, proto.Equal(pb, &got))
+	assert.Equal(t, testExt{AssociatedData: []byte{1}}, ext)
+}
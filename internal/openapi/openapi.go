@@ -0,0 +1,91 @@
+// Package openapi derives OpenAPI 3.1 schema objects from proto descriptors.
+//
+// The schemas describe the protojson (camelCase) shape the facades emit:
+// properties use the field's JSON name, bytes are base64 strings
+// ("format": "byte"), and 64-bit integers are strings as protojson writes
+// them.
+package openapi
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema returns the schema object for messages described by md. Nested
+// messages are inlined.
+func Schema(md protoreflect.MessageDescriptor) map[string]any {
+	return messageSchema(md, map[protoreflect.FullName]bool{})
+}
+
+func messageSchema(md protoreflect.MessageDescriptor, visiting map[protoreflect.FullName]bool) map[string]any {
+	if visiting[md.FullName()] {
+		// Recursive message: stop at an untyped object.
+		return map[string]any{"type": "object"}
+	}
+	visiting[md.FullName()] = true
+	defer delete(visiting, md.FullName())
+
+	properties := map[string]any{}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[fd.JSONName()] = fieldSchema(fd, visiting)
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor, visiting map[protoreflect.FullName]bool) map[string]any {
+	switch {
+	case fd.IsMap():
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": singularSchema(fd.MapValue(), visiting),
+		}
+	case fd.IsList():
+		return map[string]any{
+			"type":  "array",
+			"items": singularSchema(fd, visiting),
+		}
+	}
+	return singularSchema(fd, visiting)
+}
+
+func singularSchema(fd protoreflect.FieldDescriptor, visiting map[protoreflect.FullName]bool) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(fd.Message(), visiting)
+	}
+	return map[string]any{}
+}
+
+// Properties returns the properties map of a schema built by Schema, for
+// callers that add fields the proto does not declare.
+func Properties(schema map[string]any) map[string]any {
+	return schema["properties"].(map[string]any)
+}
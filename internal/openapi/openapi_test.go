@@ -0,0 +1,39 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	notificationv1 "github.com/tinywideclouds/gen-platform/go/types/notification/v1"
+	routingv1 "github.com/tinywideclouds/gen-platform/go/types/routing/v1"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("Scalars, maps and nested messages", func(t *testing.T) {
+		schema := Schema((&notificationv1.NotificationRequestPb{}).ProtoReflect().Descriptor())
+
+		assert.Equal(t, "object", schema["type"])
+		props := Properties(schema)
+		assert.Equal(t, map[string]any{"type": "string"}, props["recipientId"])
+		assert.Equal(t, map[string]any{
+			"type":                 "object",
+			"additionalProperties": map[string]any{"type": "string"},
+		}, props["dataPayload"])
+
+		content := props["content"].(map[string]any)
+		assert.Contains(t, Properties(content), "title")
+	})
+
+	t.Run("Repeated messages and bytes", func(t *testing.T) {
+		schema := Schema((&routingv1.QueuedMessageListPb{}).ProtoReflect().Descriptor())
+
+		messages := Properties(schema)["messages"].(map[string]any)
+		assert.Equal(t, "array", messages["type"])
+
+		envelope := Properties(messages["items"].(map[string]any))["envelope"].(map[string]any)
+		envProps := Properties(envelope)
+		assert.Equal(t, map[string]any{"type": "string", "format": "byte"}, envProps["encryptedData"])
+		assert.Equal(t, map[string]any{"type": "integer", "format": "int32"}, envProps["priority"])
+		assert.Equal(t, map[string]any{"type": "boolean"}, envProps["isEphemeral"])
+	})
+}
@@ -0,0 +1,200 @@
+// Package pbtree converts gen-platform messages to and from plain value
+// trees, for the facades' binary encodings (msgpack, CBOR).
+//
+// A message becomes a map[string]any keyed by the fields' JSON names, the
+// same keys as the protojson form, holding only populated fields. Bytes
+// fields stay []byte so the encoder can write them raw; enums are their
+// numbers. On the way back integers may be any Go integer type and unknown
+// keys are ignored.
+package pbtree
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FromMessage returns the value tree for m.
+func FromMessage(m protoreflect.Message) (map[string]any, error) {
+	out := map[string]any{}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		var ev any
+		switch {
+		case fd.IsList():
+			list := v.List()
+			items := make([]any, list.Len())
+			for i := range items {
+				if items[i], err = fromSingular(fd, list.Get(i)); err != nil {
+					return false
+				}
+			}
+			ev = items
+		case fd.IsMap():
+			entries := map[string]any{}
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				entries[k.String()], err = fromSingular(fd.MapValue(), mv)
+				return err == nil
+			})
+			ev = entries
+		default:
+			ev, err = fromSingular(fd, v)
+		}
+		if err != nil {
+			return false
+		}
+		out[fd.JSONName()] = ev
+		return true
+	})
+	return out, err
+}
+
+func fromSingular(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return FromMessage(v.Message())
+	case protoreflect.EnumKind:
+		return int64(v.Enum()), nil
+	case protoreflect.BytesKind:
+		return v.Bytes(), nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// ToMessage sets the fields of m from the value tree in. Nested maps must be
+// map[string]any.
+func ToMessage(in map[string]any, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	for key, raw := range in {
+		fd := fields.ByJSONName(key)
+		if fd == nil || raw == nil {
+			continue
+		}
+		switch {
+		case fd.IsList():
+			items, ok := raw.([]any)
+			if !ok {
+				return fmt.Errorf("field %s: expected array, got %T", key, raw)
+			}
+			list := m.Mutable(fd).List()
+			for _, item := range items {
+				v, err := toSingular(fd, item, list.NewElement)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", key, err)
+				}
+				list.Append(v)
+			}
+		case fd.IsMap():
+			entries, ok := raw.(map[string]any)
+			if !ok {
+				return fmt.Errorf("field %s: expected map, got %T", key, raw)
+			}
+			mp := m.Mutable(fd).Map()
+			for k, item := range entries {
+				v, err := toSingular(fd.MapValue(), item, mp.NewValue)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", key, err)
+				}
+				mp.Set(protoreflect.ValueOfString(k).MapKey(), v)
+			}
+		default:
+			v, err := toSingular(fd, raw, func() protoreflect.Value { return m.NewField(fd) })
+			if err != nil {
+				return fmt.Errorf("field %s: %w", key, err)
+			}
+			m.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func toSingular(fd protoreflect.FieldDescriptor, raw any, newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		sub, ok := raw.(map[string]any)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected map, got %T", raw)
+		}
+		v := newMessage()
+		return v, ToMessage(sub, v.Message())
+	case protoreflect.StringKind:
+		s, ok := raw.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected string, got %T", raw)
+		}
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		b, ok := raw.([]byte)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected bin, got %T", raw)
+		}
+		return protoreflect.ValueOfBytes(b), nil
+	case protoreflect.BoolKind:
+		b, ok := raw.(bool)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected bool, got %T", raw)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, ok := toFloat(raw)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected number, got %T", raw)
+		}
+		if fd.Kind() == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+	}
+
+	n, ok := toInt(raw)
+	if !ok {
+		return protoreflect.Value{}, fmt.Errorf("expected integer, got %T", raw)
+	}
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(n)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported kind %v", fd.Kind())
+}
+
+func toInt(raw any) (int64, bool) {
+	switch n := raw.(type) {
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func toFloat(raw any) (float64, bool) {
+	switch f := raw.(type) {
+	case float32:
+		return float64(f), true
+	case float64:
+		return f, true
+	}
+	n, ok := toInt(raw)
+	return float64(n), ok
+}
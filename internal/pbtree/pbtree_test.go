@@ -0,0 +1,53 @@
+package pbtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	routingv1 "github.com/tinywideclouds/gen-platform/go/types/routing/v1"
+	smv1 "github.com/tinywideclouds/gen-platform/go/types/secure/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFromMessage(t *testing.T) {
+	tree, err := FromMessage((&routingv1.QueuedMessagePb{
+		Id: "msg-1",
+		Envelope: &smv1.SecureEnvelopePb{
+			RecipientId:   "urn:sm:user:bob",
+			EncryptedData: []byte{1, 2},
+			Priority:      proto.Int32(0),
+		},
+	}).ProtoReflect())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id": "msg-1",
+		"envelope": map[string]any{
+			"recipientId":   "urn:sm:user:bob",
+			"encryptedData": []byte{1, 2},
+			"priority":      int32(0),
+		},
+	}, tree)
+}
+
+func TestToMessage(t *testing.T) {
+	t.Run("Any integer type", func(t *testing.T) {
+		for _, n := range []any{int8(5), uint8(5), int64(5), uint64(5)} {
+			var pb smv1.SecureEnvelopePb
+			require.NoError(t, ToMessage(map[string]any{"priority": n}, pb.ProtoReflect()))
+			assert.Equal(t, int32(5), pb.GetPriority())
+		}
+	})
+
+	t.Run("Type mismatch names the field", func(t *testing.T) {
+		var pb smv1.SecureEnvelopePb
+		err := ToMessage(map[string]any{"isEphemeral": "yes"}, pb.ProtoReflect())
+		assert.ErrorContains(t, err, "field isEphemeral: expected bool")
+	})
+
+	t.Run("Nil values and unknown keys are skipped", func(t *testing.T) {
+		var pb smv1.SecureEnvelopePb
+		require.NoError(t, ToMessage(map[string]any{"signature": nil, "extra": 1}, pb.ProtoReflect()))
+		assert.True(t, proto.Equal(&smv1.SecureEnvelopePb{}, &pb))
+	})
+}
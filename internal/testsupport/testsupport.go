@@ -0,0 +1,36 @@
+// Package testsupport holds assertions shared by the v1 facade packages'
+// tests, so each package checks the JSON facade the same way.
+package testsupport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertJSONRoundTrip marshals value with encoding/json, unmarshals the bytes
+// into a fresh T and asserts the result equals value. It returns the bytes
+// so callers can check the wire form further.
+//
+// It also marshals &value and asserts the bytes match. A MarshalJSON
+// declared on the pointer receiver is skipped when a T is marshaled by
+// value, silently falling back to the default struct encoding; this catches
+// that regression for any T.
+func AssertJSONRoundTrip[T any](t testing.TB, value T) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	require.NoError(t, err, "marshal %T", value)
+
+	viaPointer, err := json.Marshal(&value)
+	require.NoError(t, err, "marshal *%T", value)
+	assert.JSONEq(t, string(viaPointer), string(data),
+		"%T marshals differently by value and by pointer; is MarshalJSON on the pointer receiver?", value)
+
+	var decoded T
+	require.NoError(t, json.Unmarshal(data, &decoded), "unmarshal %T from %s", value, data)
+	assert.Equal(t, value, decoded, "%T changed in the JSON round trip via %s", value, data)
+	return data
+}
@@ -0,0 +1,80 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// valueFacade marshals through a value-receiver MarshalJSON, like the facades.
+type valueFacade struct{ Name string }
+
+func (v valueFacade) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"n": v.Name})
+}
+
+func (v *valueFacade) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	v.Name = m["n"]
+	return nil
+}
+
+// pointerFacade has the regression: MarshalJSON on the pointer receiver.
+type pointerFacade struct{ Name string }
+
+func (p *pointerFacade) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"n": p.Name})
+}
+
+// recorder captures failures instead of reporting them to the embedded test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()                           {}
+func (r *recorder) Errorf(format string, args ...any) { r.failed = true }
+func (r *recorder) FailNow() {
+	r.failed = true
+	runtime.Goexit()
+}
+
+// run calls fn with a recorder on its own goroutine, so FailNow can exit it.
+func run(t *testing.T, fn func(testing.TB)) bool {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.failed
+}
+
+func TestAssertJSONRoundTrip(t *testing.T) {
+	t.Run("Value receiver passes", func(t *testing.T) {
+		data := AssertJSONRoundTrip(t, valueFacade{Name: "a"})
+		assert.JSONEq(t, `{"n":"a"}`, string(data))
+	})
+
+	t.Run("Plain struct passes", func(t *testing.T) {
+		AssertJSONRoundTrip(t, struct{ A, B int }{1, 2})
+	})
+
+	t.Run("Pointer receiver fails", func(t *testing.T) {
+		assert.True(t, run(t, func(tb testing.TB) {
+			AssertJSONRoundTrip(tb, pointerFacade{Name: "a"})
+		}))
+	})
+
+	t.Run("Lossy round trip fails", func(t *testing.T) {
+		assert.True(t, run(t, func(tb testing.TB) {
+			AssertJSONRoundTrip(tb, struct{ hidden int }{1})
+		}))
+	})
+}
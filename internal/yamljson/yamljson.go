@@ -0,0 +1,68 @@
+// Package yamljson gives the protojson-backed facades a YAML form by way of
+// their JSON form, so both encodings share one set of field names and
+// conversions.
+//
+// MarshalYAML implementations return Marshal(v), a plain value tree that
+// yaml.v3 encodes; UnmarshalYAML implementations call Unmarshal, which turns
+// the YAML node back into JSON and hands it to the type's UnmarshalJSON.
+package yamljson
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal returns the JSON form of v as a value tree for yaml.v3.
+// Integers stay integers rather than becoming float64.
+func Marshal(v json.Marshaler) (any, error) {
+	data, err := v.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return fromNumbers(tree), nil
+}
+
+// Unmarshal decodes node and passes its JSON encoding to v.UnmarshalJSON.
+// A YAML null leaves v untouched, as JSON null does for the facades.
+func Unmarshal(node *yaml.Node, v json.Unmarshaler) error {
+	if node.ShortTag() == "!!null" {
+		return nil
+	}
+	var tree any
+	if err := node.Decode(&tree); err != nil {
+		return err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalJSON(data)
+}
+
+func fromNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, e := range t {
+			t[k] = fromNumbers(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = fromNumbers(e)
+		}
+	}
+	return v
+}
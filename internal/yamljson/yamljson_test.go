@@ -0,0 +1,51 @@
+package yamljson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type raw struct{ json.RawMessage }
+
+func (r raw) MarshalJSON() ([]byte, error) { return r.RawMessage, nil }
+
+func (r *raw) UnmarshalJSON(data []byte) error {
+	r.RawMessage = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func TestMarshal(t *testing.T) {
+	tree, err := Marshal(raw{json.RawMessage(`{"a":1,"b":[1.5,"x"],"c":{"d":1700000000000}}`)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a": int64(1),
+		"b": []any{1.5, "x"},
+		"c": map[string]any{"d": int64(1700000000000)},
+	}, tree)
+
+	out, err := yaml.Marshal(tree)
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1\nb:\n    - 1.5\n    - x\nc:\n    d: 1700000000000\n", string(out))
+}
+
+func TestUnmarshal(t *testing.T) {
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte("a: 1\nb: [x, true]\n"), &node))
+
+	var r raw
+	require.NoError(t, Unmarshal(node.Content[0], &r))
+	assert.JSONEq(t, `{"a":1,"b":["x",true]}`, string(r.RawMessage))
+}
+
+func TestUnmarshal_Null(t *testing.T) {
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte("~"), &node))
+
+	r := raw{json.RawMessage(`{"kept":true}`)}
+	require.NoError(t, Unmarshal(node.Content[0], &r))
+	assert.JSONEq(t, `{"kept":true}`, string(r.RawMessage))
+}
@@ -0,0 +1,15 @@
+// Package facade defines the interfaces shared by the v1 domain types, so
+// generic middleware can handle any of them without knowing the concrete
+// package.
+package facade
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// Protoer is implemented by domain types that have a gen-platform Protobuf
+// form. ToProtoMessage returns the same message as the package's ToProto
+// function; it shares byte slices with the receiver rather than copying them.
+type Protoer interface {
+	ToProtoMessage() proto.Message
+}
@@ -0,0 +1,121 @@
+package facade_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tinywideclouds/go-platform/pkg/facade/v1"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/routing/v1"
+	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+	name "github.com/tinywideclouds/go-platform/pkg/user/v1"
+)
+
+func TestProtoer(t *testing.T) {
+	recipient, err := urn.Parse("urn:sm:user:bob")
+	require.NoError(t, err)
+	env := &secure.SecureEnvelope{RecipientID: recipient, EncryptedData: []byte{1, 2, 3}}
+
+	values := []facade.Protoer{
+		*env,
+		routing.QueuedMessage{ID: "msg-1", Envelope: env},
+		name.User{ID: recipient, Alias: "bob"},
+		keys.PublicKeys{EncKey: []byte{4}, SigKey: []byte{5}},
+	}
+
+	for _, v := range values {
+		msg := v.ToProtoMessage()
+		require.NotNil(t, msg)
+
+		data, err := proto.Marshal(msg)
+		require.NoError(t, err, "%T", v)
+		assert.NotEmpty(t, data, "%T", v)
+
+		// The bytes decode back into an equal message of the same type
+		decoded := msg.ProtoReflect().New().Interface()
+		require.NoError(t, proto.Unmarshal(data, decoded))
+		assert.True(t, proto.Equal(msg, decoded), "%T", v)
+	}
+}
+
+// TestValueReceiverMarshalJSON guards the value-receiver MarshalJSON on every
+// facade: a regression to a pointer receiver leaves slice and map elements
+// marshaled by the default struct encoder, with Go field names.
+func TestValueReceiverMarshalJSON(t *testing.T) {
+	bob, err := urn.Parse("urn:sm:user:bob")
+	require.NoError(t, err)
+	alice, err := urn.Parse("urn:sm:user:alice")
+	require.NoError(t, err)
+
+	users := []name.User{{ID: bob, Alias: "bob"}, {ID: alice, Alias: "alice"}}
+	keySets := []keys.PublicKeys{{EncKey: []byte{1}, SigKey: []byte{2}}, {EncKey: []byte{3}, SigKey: []byte{4}}}
+	urns := []urn.URN{bob, alice}
+	envelopes := []secure.SecureEnvelope{
+		{RecipientID: bob, EncryptedData: []byte{1, 2, 3}},
+		{RecipientID: alice, EncryptedData: []byte{4, 5, 6}},
+	}
+
+	testCases := []struct {
+		name     string
+		elements []json.Marshaler
+		slice    any
+		byKey    any
+		want     string // a member or value only the custom marshaler writes
+	}{
+		{"User", marshalers(users), users, map[string]name.User{"bob": users[0], "alice": users[1]}, `"alias":"bob"`},
+		{"PublicKeys", marshalers(keySets), keySets, map[string]keys.PublicKeys{"bob": keySets[0], "alice": keySets[1]}, `"encKey":"AQ=="`},
+		{"URN", marshalers(urns), urns, map[string]urn.URN{"bob": urns[0], "alice": urns[1]}, `"urn:sm:user:bob"`},
+		{"SecureEnvelope", marshalers(envelopes), envelopes, map[string]secure.SecureEnvelope{"bob": envelopes[0], "alice": envelopes[1]}, `"recipientId":"urn:sm:user:bob"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Each element's own output, via the value receiver
+			var want []json.RawMessage
+			for _, el := range tc.elements {
+				data, err := el.MarshalJSON()
+				require.NoError(t, err)
+				want = append(want, data)
+			}
+
+			t.Run("Slice", func(t *testing.T) {
+				data, err := json.Marshal(tc.slice)
+				require.NoError(t, err)
+				assert.Contains(t, string(data), tc.want)
+
+				var got []json.RawMessage
+				require.NoError(t, json.Unmarshal(data, &got))
+				require.Len(t, got, len(want))
+				for i := range want {
+					assert.JSONEq(t, string(want[i]), string(got[i]))
+				}
+			})
+
+			t.Run("Map values", func(t *testing.T) {
+				data, err := json.Marshal(tc.byKey)
+				require.NoError(t, err)
+				assert.Contains(t, string(data), tc.want)
+
+				var got map[string]json.RawMessage
+				require.NoError(t, json.Unmarshal(data, &got))
+				assert.JSONEq(t, string(want[0]), string(got["bob"]))
+				assert.JSONEq(t, string(want[1]), string(got["alice"]))
+			})
+		})
+	}
+}
+
+// marshalers converts a slice of values to json.Marshalers. It only compiles
+// for types whose MarshalJSON has a value receiver.
+func marshalers[T json.Marshaler](values []T) []json.Marshaler {
+	out := make([]json.Marshaler, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package keys
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tinywideclouds/go-platform/internal/convert"
+	"github.com/tinywideclouds/go-platform/internal/jsonext"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// VersionedKeys is one published key pair within a KeyBundle.
+//
+// Its JSON form is the PublicKeys object with "keyId" added. The expiry of a
+// bundle entry is Keys.ExpiresAt, written as the PublicKeys "expiresAt"
+// member.
+type VersionedKeys struct {
+	KeyID string
+	Keys  PublicKeys
+}
+
+// versionedKeysExt holds the VersionedKeys fields around the PublicKeys object.
+type versionedKeysExt struct {
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// IsExpired reports whether the keys have expired at now, as
+// Keys.IsExpired does.
+func (vk *VersionedKeys) IsExpired(now time.Time) bool {
+	return vk.Keys.IsExpired(now)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (vk VersionedKeys) MarshalJSON() ([]byte, error) {
+	return vk.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options,
+// applied to keyId and to the embedded PublicKeys.
+func (vk VersionedKeys) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	data, err := vk.Keys.MarshalJSONWith(opts)
+	if err != nil {
+		return nil, err
+	}
+	return jsonext.MergeWith(data, versionedKeysExt{KeyID: vk.KeyID}, opts)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (vk *VersionedKeys) UnmarshalJSON(data []byte) error {
+	var keys PublicKeys
+	if err := keys.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var ext versionedKeysExt
+	if err := jsonext.Unmarshal(data, &ext); err != nil {
+		return err
+	}
+	*vk = VersionedKeys{KeyID: ext.KeyID, Keys: keys}
+	return nil
+}
+
+// KeyBundle holds every currently published key version of one entity, in
+// publication order, so that rotation can overlap old and new keys.
+//
+// gen-platform has no proto message for bundles, so KeyBundle only has a
+// JSON facade.
+type KeyBundle struct {
+	Keys []*VersionedKeys `json:"keys,omitempty"`
+}
+
+// keyBundleJSON has KeyBundle's shape without its methods.
+type keyBundleJSON struct {
+	Keys []*VersionedKeys `json:"keys,omitempty"`
+}
+
+// ActiveKey returns the most recently published keys that have not expired,
+// or nil if there are none.
+func (kb *KeyBundle) ActiveKey() *VersionedKeys {
+	return kb.activeKeyAt(time.Now())
+}
+
+func (kb *KeyBundle) activeKeyAt(now time.Time) *VersionedKeys {
+	for i := len(kb.Keys) - 1; i >= 0; i-- {
+		if vk := kb.Keys[i]; vk != nil && !vk.IsExpired(now) {
+			return vk
+		}
+	}
+	return nil
+}
+
+// KeyByID returns the keys with the given ID, expired or not, or nil.
+func (kb *KeyBundle) KeyByID(id string) *VersionedKeys {
+	for _, vk := range kb.Keys {
+		if vk != nil && vk.KeyID == id {
+			return vk
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (kb KeyBundle) MarshalJSON() ([]byte, error) {
+	return kb.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options,
+// applied to every key version.
+func (kb KeyBundle) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	return jsonext.MarshalList("keys", kb.Keys, opts)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (kb *KeyBundle) UnmarshalJSON(data []byte) error {
+	var wire keyBundleJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*kb = KeyBundle(wire)
+	return nil
+}
@@ -0,0 +1,120 @@
+package keys
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBundle() *KeyBundle {
+	return &KeyBundle{
+		Keys: []*VersionedKeys{
+			{
+				KeyID: "v1",
+				Keys: PublicKeys{
+					EncKey:       []byte{1},
+					SigKey:       []byte{2},
+					EncAlgorithm: AlgorithmX25519,
+					SigAlgorithm: AlgorithmEd25519,
+					ExpiresAt:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				},
+			},
+			{
+				KeyID: "v2",
+				Keys: PublicKeys{
+					EncKey:       []byte{3},
+					SigKey:       []byte{4},
+					EncAlgorithm: AlgorithmX25519,
+					SigAlgorithm: AlgorithmEd25519,
+					ExpiresAt:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+				},
+			},
+		},
+	}
+}
+
+func TestKeyBundle_ActiveKey(t *testing.T) {
+	bundle := newTestBundle()
+
+	t.Run("Newest unexpired key wins", func(t *testing.T) {
+		now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, "v2", bundle.activeKeyAt(now).KeyID)
+	})
+
+	t.Run("Skips expired keys", func(t *testing.T) {
+		bundle := newTestBundle()
+		bundle.Keys[0].Keys.ExpiresAt = 0 // never expires
+		now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, "v1", bundle.activeKeyAt(now).KeyID)
+	})
+
+	t.Run("All expired", func(t *testing.T) {
+		now := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.Nil(t, bundle.activeKeyAt(now))
+	})
+
+	t.Run("Empty bundle", func(t *testing.T) {
+		assert.Nil(t, (&KeyBundle{}).ActiveKey())
+	})
+}
+
+func TestKeyBundle_KeyByID(t *testing.T) {
+	bundle := newTestBundle()
+
+	found := bundle.KeyByID("v1")
+	require.NotNil(t, found)
+	assert.Equal(t, []byte{1}, found.Keys.EncKey)
+
+	assert.Nil(t, bundle.KeyByID("v3"))
+}
+
+func TestKeyBundle_JSON_RoundTrip(t *testing.T) {
+	bundle := newTestBundle()
+
+	expectedJSON := `{
+		"keys": [
+			{"keyId":"v1","expiresAt":1735689600000,"encKey":"AQ==","sigKey":"Ag==","encAlgorithm":"X25519","sigAlgorithm":"Ed25519"},
+			{"keyId":"v2","expiresAt":1767225600000,"encKey":"Aw==","sigKey":"BA==","encAlgorithm":"X25519","sigAlgorithm":"Ed25519"}
+		]
+	}`
+
+	jsonBytes, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	assert.JSONEq(t, expectedJSON, string(jsonBytes))
+
+	var result KeyBundle
+	require.NoError(t, json.Unmarshal(jsonBytes, &result))
+	assert.Equal(t, bundle, &result)
+}
+
+func TestVersionedKeys_ExpiresAt(t *testing.T) {
+	vk := VersionedKeys{
+		KeyID: "v3",
+		Keys: PublicKeys{
+			EncKey:       []byte{1},
+			SigKey:       []byte{2},
+			EncAlgorithm: AlgorithmX25519,
+			SigAlgorithm: AlgorithmEd25519,
+			CreatedAt:    1000,
+			ExpiresAt:    1500,
+		},
+	}
+	data, err := json.Marshal(vk)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), `"expiresAt":1500`), string(data))
+
+	var got VersionedKeys
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, vk, got)
+
+	assert.False(t, got.IsExpired(time.UnixMilli(1499)))
+	assert.True(t, got.IsExpired(time.UnixMilli(1500)))
+
+	bundle := &KeyBundle{Keys: []*VersionedKeys{&got}}
+	assert.Same(t, &got, bundle.activeKeyAt(time.UnixMilli(1499)))
+	assert.Nil(t, bundle.activeKeyAt(time.UnixMilli(1500)), "an entry expires with its PublicKeys")
+}
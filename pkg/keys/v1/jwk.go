@@ -0,0 +1,82 @@
+package keys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// jwk is the public-key subset of RFC 7517 / RFC 8037 JSON Web Keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jwkCurves maps our algorithm names to their JWK kty, crv and alg.
+var jwkCurves = map[string]struct{ kty, crv, alg string }{
+	AlgorithmX25519:    {"OKP", "X25519", "ECDH-ES"},
+	AlgorithmX448:      {"OKP", "X448", "ECDH-ES"},
+	AlgorithmECDHP256:  {"EC", "P-256", "ECDH-ES"},
+	AlgorithmEd25519:   {"OKP", "Ed25519", "EdDSA"},
+	AlgorithmEd448:     {"OKP", "Ed448", "EdDSA"},
+	AlgorithmECDSAP256: {"EC", "P-256", "ES256"},
+}
+
+// ToJWKSet exports the keys as a JWK Set for WebCrypto clients: one key with
+// "use": "enc" for EncKey and one with "use": "sig" for SigKey, both with
+// "kid" set to the Fingerprint. Absent keys are left out; an unset algorithm
+// means the default. Only public material is emitted. P-256 keys must be
+// uncompressed points.
+func (pk PublicKeys) ToJWKSet() ([]byte, error) {
+	kid := pk.Fingerprint()
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{Keys: []jwk{}}
+
+	for _, k := range []struct {
+		use, algorithm, fallback string
+		key                      []byte
+	}{
+		{"enc", pk.EncAlgorithm, DefaultEncAlgorithm, pk.EncKey},
+		{"sig", pk.SigAlgorithm, DefaultSigAlgorithm, pk.SigKey},
+	} {
+		if len(k.key) == 0 {
+			continue
+		}
+		algorithm := k.algorithm
+		if algorithm == "" {
+			algorithm = k.fallback
+		}
+		key, err := toJWK(algorithm, k.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s key: %w", k.use, err)
+		}
+		key.Use = k.use
+		key.Kid = kid
+		set.Keys = append(set.Keys, key)
+	}
+	return json.Marshal(set)
+}
+
+func toJWK(algorithm string, key []byte) (jwk, error) {
+	curve, ok := jwkCurves[algorithm]
+	if !ok {
+		return jwk{}, fmt.Errorf("%w: no JWK mapping for algorithm %q", ErrInvalidKey, algorithm)
+	}
+	out := jwk{Kty: curve.kty, Crv: curve.crv, Alg: curve.alg}
+	if curve.kty == "EC" {
+		if len(key) != 65 || key[0] != 0x04 {
+			return jwk{}, fmt.Errorf("%w: %s key is not an uncompressed point", ErrInvalidKey, algorithm)
+		}
+		out.X = base64.RawURLEncoding.EncodeToString(key[1:33])
+		out.Y = base64.RawURLEncoding.EncodeToString(key[33:])
+		return out, nil
+	}
+	out.X = base64.RawURLEncoding.EncodeToString(key)
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testJWKSet struct {
+	Keys []map[string]string `json:"keys"`
+}
+
+func TestPublicKeys_ToJWKSet(t *testing.T) {
+	t.Run("X25519 and Ed25519", func(t *testing.T) {
+		encPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		sigPub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		pk := PublicKeys{
+			EncKey:       encPriv.PublicKey().Bytes(),
+			SigKey:       sigPub,
+			EncAlgorithm: AlgorithmX25519,
+			SigAlgorithm: AlgorithmEd25519,
+		}
+
+		data, err := pk.ToJWKSet()
+		require.NoError(t, err)
+
+		var set testJWKSet
+		require.NoError(t, json.Unmarshal(data, &set))
+		require.Len(t, set.Keys, 2)
+
+		enc, sig := set.Keys[0], set.Keys[1]
+		assert.Equal(t, map[string]string{
+			"kty": "OKP", "crv": "X25519", "use": "enc", "alg": "ECDH-ES",
+			"x":   base64.RawURLEncoding.EncodeToString(pk.EncKey),
+			"kid": pk.Fingerprint(),
+		}, enc)
+		assert.Equal(t, map[string]string{
+			"kty": "OKP", "crv": "Ed25519", "use": "sig", "alg": "EdDSA",
+			"x":   base64.RawURLEncoding.EncodeToString(pk.SigKey),
+			"kid": pk.Fingerprint(),
+		}, sig)
+
+		// Only public members are present
+		for _, key := range set.Keys {
+			assert.NotContains(t, key, "d")
+		}
+	})
+
+	t.Run("P-256 splits the point into x and y", func(t *testing.T) {
+		encPriv, err := ecdh.P256().GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		point := encPriv.PublicKey().Bytes()
+		pk := PublicKeys{EncKey: point, EncAlgorithm: AlgorithmECDHP256}
+
+		data, err := pk.ToJWKSet()
+		require.NoError(t, err)
+
+		var set testJWKSet
+		require.NoError(t, json.Unmarshal(data, &set))
+		require.Len(t, set.Keys, 1)
+		x, err := base64.RawURLEncoding.DecodeString(set.Keys[0]["x"])
+		require.NoError(t, err)
+		y, err := base64.RawURLEncoding.DecodeString(set.Keys[0]["y"])
+		require.NoError(t, err)
+		assert.Equal(t, point, bytes.Join([][]byte{{0x04}, x, y}, nil))
+		assert.Equal(t, "EC", set.Keys[0]["kty"])
+	})
+
+	t.Run("Unset algorithms use the defaults", func(t *testing.T) {
+		data, err := PublicKeys{EncKey: []byte{1}, SigKey: []byte{2}}.ToJWKSet()
+		require.NoError(t, err)
+		var set testJWKSet
+		require.NoError(t, json.Unmarshal(data, &set))
+		assert.Equal(t, "X25519", set.Keys[0]["crv"])
+		assert.Equal(t, "Ed25519", set.Keys[1]["crv"])
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := PublicKeys{EncKey: []byte{1}, EncAlgorithm: "RSA-OAEP"}.ToJWKSet()
+		assert.ErrorIs(t, err, ErrInvalidKey)
+
+		_, err = PublicKeys{SigKey: []byte{1, 2, 3}, SigAlgorithm: AlgorithmECDSAP256}.ToJWKSet()
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+
+	t.Run("No keys", func(t *testing.T) {
+		data, err := PublicKeys{}.ToJWKSet()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"keys":[]}`, string(data))
+	})
+}
@@ -1,27 +1,96 @@
 package keys
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	keysv1 "github.com/tinywideclouds/gen-platform/go/types/keys/v1"
+	"github.com/tinywideclouds/go-platform/internal/convert"
+	"github.com/tinywideclouds/go-platform/internal/jsonext"
+	"github.com/tinywideclouds/go-platform/internal/openapi"
+	"github.com/tinywideclouds/go-platform/internal/yamljson"
+	"github.com/tinywideclouds/go-platform/pkg/facade/v1"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
 )
 
-// --- Marshal/Unmarshal Options (Unchanged) ---
-var (
-	protojsonMarshalOptions = &protojson.MarshalOptions{
-		UseProtoNames:   false, // Use camelCase
-		EmitUnpopulated: false,
-	}
-	protojsonUnmarshalOptions = &protojson.UnmarshalOptions{
-		DiscardUnknown: true,
-	}
+// ErrInvalidKey is returned by Validate for a missing or malformed key.
+var ErrInvalidKey = errors.New("invalid public key")
+
+// KeyLengths are the expected sizes, in bytes, of the two keys. A zero
+// length only requires the key to be present.
+type KeyLengths struct {
+	EncKey int
+	SigKey int
+}
+
+// DefaultKeyLengths matches X25519 and Ed25519 public keys.
+var DefaultKeyLengths = KeyLengths{EncKey: 32, SigKey: 32}
+
+// Key algorithms. The defaults are what every key published before the
+// algorithm fields existed uses.
+const (
+	AlgorithmX25519    = "X25519"
+	AlgorithmX448      = "X448"
+	AlgorithmECDHP256  = "ECDH-P256"
+	AlgorithmEd25519   = "Ed25519"
+	AlgorithmEd448     = "Ed448"
+	AlgorithmECDSAP256 = "ECDSA-P256"
+
+	DefaultEncAlgorithm = AlgorithmX25519
+	DefaultSigAlgorithm = AlgorithmEd25519
 )
 
+// PublicKeys is a published encryption and signing key pair.
+//
+// An absent key is always nil, never []byte{}: FromProto, UnmarshalJSON and
+// the setters all normalize an empty key to nil, so decoded values compare
+// reliably with reflect.DeepEqual and in storage.
 type PublicKeys struct {
 	EncKey []byte `json:"encKey,omitempty"`
 	SigKey []byte `json:"sigKey,omitempty"`
+
+	// EncAlgorithm and SigAlgorithm are not part of PublicKeysPb yet: ToProto
+	// drops them, FromProto sets the defaults, and the JSON facade carries
+	// them alongside the protojson fields.
+	EncAlgorithm string `json:"encAlgorithm,omitempty"`
+	SigAlgorithm string `json:"sigAlgorithm,omitempty"`
+
+	// CreatedAt and ExpiresAt bound the key set's lifetime, in Unix
+	// milliseconds; zero means unset, and an unset ExpiresAt never expires.
+	// Like the algorithms, they travel only in the JSON facade, and key sets
+	// published before they existed decode with both zero.
+	CreatedAt int64 `json:"createdAt,omitempty"`
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// publicKeysExt holds the PublicKeys fields that PublicKeysPb cannot carry.
+type publicKeysExt struct {
+	EncAlgorithm string `json:"encAlgorithm,omitempty"`
+	SigAlgorithm string `json:"sigAlgorithm,omitempty"`
+	CreatedAt    int64  `json:"createdAt,omitempty"`
+	ExpiresAt    int64  `json:"expiresAt,omitempty"`
+}
+
+// IsExpired reports whether the key set has expired at now. A key set
+// without ExpiresAt never expires.
+func (pk PublicKeys) IsExpired(now time.Time) bool {
+	return pk.ExpiresAt != 0 && now.UnixMilli() >= pk.ExpiresAt
 }
 
 // ToProto converts the idiomatic Go struct into its Protobuf representation.
+// PublicKeysPb has only the keys, so the algorithms, CreatedAt and ExpiresAt
+// are dropped until gen-platform adds them; use the JSON facade to keep them.
 func ToProto(native *PublicKeys) *keysv1.PublicKeysPb {
 	if native == nil {
 		return nil
@@ -32,17 +101,35 @@ func ToProto(native *PublicKeys) *keysv1.PublicKeysPb {
 	}
 }
 
+// ToProtoMessage implements facade.Protoer, returning ToProto(&pk) as a
+// *PublicKeysPb.
+func (pk PublicKeys) ToProtoMessage() proto.Message {
+	return ToProto(&pk)
+}
+
+var _ facade.Protoer = PublicKeys{}
+
 // FromProto converts the Protobuf representation into the idiomatic Go struct.
 func FromProto(proto *keysv1.PublicKeysPb) (*PublicKeys, error) {
 	if proto == nil {
 		return nil, nil
 	}
 	return &PublicKeys{
-		EncKey: proto.EncKey,
-		SigKey: proto.SigKey,
+		EncKey:       nilIfEmpty(proto.EncKey),
+		SigKey:       nilIfEmpty(proto.SigKey),
+		EncAlgorithm: DefaultEncAlgorithm,
+		SigAlgorithm: DefaultSigAlgorithm,
 	}, nil
 }
 
+// nilIfEmpty applies the PublicKeys absent-key policy.
+func nilIfEmpty(key []byte) []byte {
+	if len(key) == 0 {
+		return nil
+	}
+	return key
+}
+
 // --- JSON METHODS ---
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -51,12 +138,42 @@ func FromProto(proto *keysv1.PublicKeysPb) (*PublicKeys, error) {
 // This means both PublicKeys and *PublicKeys satisfy the interface,
 // making our API robust and removing the "fragility".
 func (pk PublicKeys) MarshalJSON() ([]byte, error) {
+	return pk.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options, e.g.
+// UseProtoNames and EmitUnpopulated for a debug endpoint. The options apply
+// to the algorithm and lifetime fields as well as to the PublicKeysPb
+// fields.
+func (pk PublicKeys) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
 	// 1. Convert native Go struct to Protobuf struct
 	// Note: We pass a pointer to ToProto
 	protoPb := ToProto(&pk)
 
-	// 2. Marshal using our camelCase options
-	return protojsonMarshalOptions.Marshal(protoPb)
+	// 2. Marshal using the given options
+	data, err := opts.Marshal(protoPb)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Append the fields PublicKeysPb does not carry
+	return jsonext.MergeWith(data, publicKeysExt{
+		EncAlgorithm: pk.EncAlgorithm,
+		SigAlgorithm: pk.SigAlgorithm,
+		CreatedAt:    pk.CreatedAt,
+		ExpiresAt:    pk.ExpiresAt,
+	}, opts)
+}
+
+// MarshalCanonical returns the JSON form with sorted keys and no
+// insignificant whitespace, so equal values always give identical bytes.
+// Use it for hashing and signing rather than MarshalJSON.
+func (pk PublicKeys) MarshalCanonical() ([]byte, error) {
+	data, err := pk.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jsonext.Canonical(data)
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -65,7 +182,7 @@ func (pk PublicKeys) MarshalJSON() ([]byte, error) {
 func (pk *PublicKeys) UnmarshalJSON(data []byte) error {
 	var protoPb keysv1.PublicKeysPb
 
-	if err := protojsonUnmarshalOptions.Unmarshal(data, &protoPb); err != nil {
+	if err := convert.UnmarshalOptions().Unmarshal(data, &protoPb); err != nil {
 		return err
 	}
 
@@ -74,10 +191,292 @@ func (pk *PublicKeys) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	if native != nil {
-		*pk = *native
-	} else {
-		*pk = PublicKeys{}
+	var ext publicKeysExt
+	if err := jsonext.Unmarshal(data, &ext); err != nil {
+		return err
+	}
+	if ext.EncAlgorithm != "" {
+		native.EncAlgorithm = ext.EncAlgorithm
+	}
+	if ext.SigAlgorithm != "" {
+		native.SigAlgorithm = ext.SigAlgorithm
+	}
+	native.CreatedAt = ext.CreatedAt
+	native.ExpiresAt = ext.ExpiresAt
+
+	*pk = *native
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface using the JSON form.
+func (pk PublicKeys) MarshalYAML() (any, error) {
+	return yamljson.Marshal(pk)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface using the JSON form.
+func (pk *PublicKeys) UnmarshalYAML(node *yaml.Node) error {
+	return yamljson.Unmarshal(node, pk)
+}
+
+// --- KeyList (List) ---
+
+// KeyList is the idiomatic Go struct for a list of key sets, as returned by a
+// bulk directory fetch.
+type KeyList struct {
+	Keys []*PublicKeys `json:"keys,omitempty"`
+}
+
+// keyListJSON has KeyList's shape without its methods, so encoding/json uses
+// the PublicKeys facade for each element.
+type keyListJSON struct {
+	Keys []*PublicKeys `json:"keys,omitempty"`
+}
+
+// ListToProto converts the idiomatic Go list into a slice of Protobuf key
+// sets. gen-platform has no list message for keys, so the slice is the wire
+// form.
+func ListToProto(native *KeyList) []*keysv1.PublicKeysPb {
+	if native == nil {
+		return nil
+	}
+	return convert.List(native.Keys, ToProto)
+}
+
+// ListFromProto converts a slice of Protobuf key sets into the idiomatic Go
+// list.
+func ListFromProto(proto []*keysv1.PublicKeysPb) (*KeyList, error) {
+	if proto == nil {
+		return nil, nil
+	}
+	nativeKeys, err := convert.ListErr(proto, FromProto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keys %w", err)
+	}
+	return &KeyList{
+		Keys: nativeKeys,
+	}, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// Each element is marshaled by the PublicKeys facade.
+func (kl KeyList) MarshalJSON() ([]byte, error) {
+	return kl.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options,
+// applied to every key set.
+func (kl KeyList) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	return jsonext.MarshalList("keys", kl.Keys, opts)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (kl *KeyList) UnmarshalJSON(data []byte) error {
+	var wire keyListJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*kl = KeyList(wire)
+	return nil
+}
+
+// --- Identity ---
+
+// fingerprintBytes is how much of the SHA-256 digest Fingerprint keeps.
+const fingerprintBytes = 16
+
+// Fingerprint returns a stable short identifier for the key pair: the first
+// 16 bytes, hex encoded, of SHA-256(len(EncKey) || EncKey || len(SigKey) || SigKey)
+// with big-endian uint32 lengths. Changing either key changes the fingerprint.
+// A PublicKeys with neither key set returns "".
+func (pk PublicKeys) Fingerprint() string {
+	if len(pk.EncKey) == 0 && len(pk.SigKey) == 0 {
+		return ""
+	}
+	digest := pk.digest()
+	return hex.EncodeToString(digest[:fingerprintBytes])
+}
+
+// digest is the full SHA-256 behind Fingerprint.
+func (pk PublicKeys) digest() []byte {
+	h := sha256.New()
+	for _, key := range [][]byte{pk.EncKey, pk.SigKey} {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(key)))
+		h.Write(key)
+	}
+	return h.Sum(nil)
+}
+
+// Safety number layout: safetyNumberGroups groups of safetyNumberDigits
+// decimal digits, each taken from 5 bytes of the digest.
+const (
+	safetyNumberGroups  = 12
+	safetyNumberDigits  = 5
+	safetyNumberContext = "tinywide.keys.v1.SafetyNumber\x00"
+)
+
+// SafetyNumber derives the code two users compare out of band to confirm
+// they hold each other's real keys, e.g. "05219 88412 ..." (12 groups of 5
+// digits). Each party's key pair is hashed as in Fingerprint and the two
+// digests are combined in sorted order, so SafetyNumber(a, b) ==
+// SafetyNumber(b, a); changing any key changes the number.
+func SafetyNumber(a, b PublicKeys) string {
+	first, second := a.digest(), b.digest()
+	if bytes.Compare(first, second) > 0 {
+		first, second = second, first
+	}
+	h := sha512.New()
+	h.Write([]byte(safetyNumberContext))
+	h.Write(first)
+	h.Write(second)
+	sum := h.Sum(nil)
+
+	groups := make([]string, safetyNumberGroups)
+	for i := range groups {
+		chunk := sum[i*5 : i*5+5]
+		var n uint64
+		for _, c := range chunk {
+			n = n<<8 | uint64(c)
+		}
+		groups[i] = fmt.Sprintf("%0*d", safetyNumberDigits, n%100000)
+	}
+	return strings.Join(groups, " ")
+}
+
+// Equal reports whether pk and other hold the same key material. A nil and an
+// empty key are both "absent" and compare equal. Algorithm labels are not
+// compared.
+func (pk PublicKeys) Equal(other PublicKeys) bool {
+	return bytes.Equal(pk.EncKey, other.EncKey) && bytes.Equal(pk.SigKey, other.SigKey)
+}
+
+// --- Display Helpers ---
+
+// DiffString describes how other differs from pk (old -> new) for audit logs,
+// e.g. "encKey changed: 3f2a9c1b07d4e5f6 -> 8be07c4410a2d93e". Keys are shown
+// as truncated SHA-256 fingerprints, never in full. A nil other is treated
+// as empty keys.
+func (pk PublicKeys) DiffString(other *PublicKeys) string {
+	if other == nil {
+		other = &PublicKeys{}
+	}
+	var changes []string
+	if !bytes.Equal(pk.EncKey, other.EncKey) {
+		changes = append(changes, fmt.Sprintf("encKey changed: %s -> %s", shortFingerprint(pk.EncKey), shortFingerprint(other.EncKey)))
+	}
+	if !bytes.Equal(pk.SigKey, other.SigKey) {
+		changes = append(changes, fmt.Sprintf("sigKey changed: %s -> %s", shortFingerprint(pk.SigKey), shortFingerprint(other.SigKey)))
+	}
+	if len(changes) == 0 {
+		return "no change"
+	}
+	return strings.Join(changes, "; ")
+}
+
+// shortFingerprint is the first 8 bytes of the key's SHA-256, in hex.
+func shortFingerprint(key []byte) string {
+	if len(key) == 0 {
+		return "(none)"
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncKeyBase64 returns EncKey as standard padded base64, as in the JSON
+// form, or "" if it is absent.
+func (pk PublicKeys) EncKeyBase64() string {
+	return base64.StdEncoding.EncodeToString(pk.EncKey)
+}
+
+// SigKeyBase64 returns SigKey as standard padded base64, or "" if it is
+// absent.
+func (pk PublicKeys) SigKeyBase64() string {
+	return base64.StdEncoding.EncodeToString(pk.SigKey)
+}
+
+// EncKeyHex returns EncKey as lowercase hex, or "" if it is absent.
+func (pk PublicKeys) EncKeyHex() string {
+	return hex.EncodeToString(pk.EncKey)
+}
+
+// SigKeyHex returns SigKey as lowercase hex, or "" if it is absent.
+func (pk PublicKeys) SigKeyHex() string {
+	return hex.EncodeToString(pk.SigKey)
+}
+
+// SetEncKeyBase64 sets EncKey from base64 in either alphabet, padded or
+// not. An empty string clears the key. On error EncKey is unchanged.
+func (pk *PublicKeys) SetEncKeyBase64(s string) error {
+	key, err := decodeKeyBase64("encKey", s)
+	if err != nil {
+		return err
+	}
+	pk.EncKey = key
+	return nil
+}
+
+// SetSigKeyBase64 is SetEncKeyBase64 for SigKey.
+func (pk *PublicKeys) SetSigKeyBase64(s string) error {
+	key, err := decodeKeyBase64("sigKey", s)
+	if err != nil {
+		return err
+	}
+	pk.SigKey = key
+	return nil
+}
+
+func decodeKeyBase64(field, s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, nil
+	}
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	key, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not valid base64: %v", ErrInvalidKey, field, err)
+	}
+	return key, nil
+}
+
+// --- Schema ---
+
+// OpenAPISchema returns the OpenAPI 3.1 schema object for the JSON form of
+// PublicKeys: the proto descriptor's fields plus the algorithm fields.
+func (pk PublicKeys) OpenAPISchema() (map[string]any, error) {
+	schema := openapi.Schema((&keysv1.PublicKeysPb{}).ProtoReflect().Descriptor())
+	props := openapi.Properties(schema)
+	props["encAlgorithm"] = map[string]any{"type": "string"}
+	props["sigAlgorithm"] = map[string]any{"type": "string"}
+	props["createdAt"] = map[string]any{"type": "integer", "format": "int64"}
+	props["expiresAt"] = map[string]any{"type": "integer", "format": "int64"}
+	return schema, nil
+}
+
+// --- Validation ---
+
+// Validate checks that both keys are present and have DefaultKeyLengths.
+func (pk PublicKeys) Validate() error {
+	return pk.ValidateLengths(DefaultKeyLengths)
+}
+
+// ValidateLengths is Validate with caller-supplied expected lengths, for
+// algorithms whose keys differ in size. Errors wrap ErrInvalidKey and name
+// the offending field.
+func (pk PublicKeys) ValidateLengths(expected KeyLengths) error {
+	if err := validateKey("encKey", pk.EncKey, expected.EncKey); err != nil {
+		return err
+	}
+	return validateKey("sigKey", pk.SigKey, expected.SigKey)
+}
+
+func validateKey(field string, key []byte, expected int) error {
+	if len(key) == 0 {
+		return fmt.Errorf("%w: %s is missing", ErrInvalidKey, field)
+	}
+	if expected > 0 && len(key) != expected {
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrInvalidKey, field, len(key), expected)
 	}
 	return nil
 }
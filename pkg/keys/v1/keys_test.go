@@ -1,23 +1,32 @@
 package keys
 
 import (
+	"bytes"
 	"encoding/json" // We use the standard 'json' lib to test the interface
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	keysv1 "github.com/tinywideclouds/gen-platform/go/types/keys/v1"
+	"github.com/tinywideclouds/go-platform/internal/testsupport"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
 )
 
 func TestPublicKeys_JSON_RoundTrip(t *testing.T) {
 	// Arrange
 	nativeStruct := &PublicKeys{
-		EncKey: []byte{1, 2, 3},
-		SigKey: []byte{4, 5, 6},
+		EncKey:       []byte{1, 2, 3},
+		SigKey:       []byte{4, 5, 6},
+		EncAlgorithm: AlgorithmX25519,
+		SigAlgorithm: AlgorithmEd25519,
 	}
 
 	// This is the JSON string that protojson *should* create
 	// (bytes are correctly marshaled as base64 strings)
-	expectedJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+	expectedJSON := `{"encKey":"AQID","sigKey":"BAUG","encAlgorithm":"X25519","sigAlgorithm":"Ed25519"}`
 
 	// --- Test 1: Marshal (Go struct -> JSON) ---
 	t.Run("MarshalJSON", func(t *testing.T) {
@@ -51,7 +60,8 @@ func TestPublicKeys_JSON_RoundTrip(t *testing.T) {
 	t.Run("UnmarshalJSON with unknown fields", func(t *testing.T) {
 		// Arrange
 		var resultStruct PublicKeys
-		// This JSON has an extra field, which our Unmarshaler should ignore
+		// This JSON has an extra field, which our Unmarshaler should ignore.
+		// It also predates the algorithm fields, which default.
 		jsonWithExtra := `{"encKey":"AQID","sigKey":"BAUG","extraField":"should_be_ignored"}`
 
 		// Act
@@ -62,3 +72,421 @@ func TestPublicKeys_JSON_RoundTrip(t *testing.T) {
 		assert.Equal(t, nativeStruct, &resultStruct)
 	})
 }
+
+func TestPublicKeys_DiffString(t *testing.T) {
+	oldKeys := PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+
+	t.Run("Identical", func(t *testing.T) {
+		same := PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+		assert.Equal(t, "no change", oldKeys.DiffString(&same))
+	})
+
+	t.Run("One changed", func(t *testing.T) {
+		newKeys := PublicKeys{EncKey: []byte{9, 9, 9}, SigKey: []byte{4, 5, 6}}
+
+		diff := oldKeys.DiffString(&newKeys)
+
+		expected := "encKey changed: " + shortFingerprint(oldKeys.EncKey) + " -> " + shortFingerprint(newKeys.EncKey)
+		assert.Equal(t, expected, diff)
+		assert.NotContains(t, diff, "sigKey")
+		assert.Len(t, shortFingerprint(oldKeys.EncKey), 16)
+	})
+
+	t.Run("Both changed", func(t *testing.T) {
+		newKeys := PublicKeys{EncKey: []byte{9, 9, 9}, SigKey: []byte{8, 8, 8}}
+
+		diff := oldKeys.DiffString(&newKeys)
+
+		parts := strings.Split(diff, "; ")
+		require.Len(t, parts, 2)
+		assert.True(t, strings.HasPrefix(parts[0], "encKey changed: "))
+		assert.True(t, strings.HasPrefix(parts[1], "sigKey changed: "))
+	})
+
+	t.Run("Removed keys", func(t *testing.T) {
+		diff := oldKeys.DiffString(nil)
+		assert.Contains(t, diff, "-> (none)")
+	})
+}
+
+func TestPublicKeys_Fingerprint(t *testing.T) {
+	pk := PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+	fingerprint := pk.Fingerprint()
+
+	t.Run("Stable", func(t *testing.T) {
+		assert.Len(t, fingerprint, 2*fingerprintBytes)
+		same := PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+		assert.Equal(t, fingerprint, same.Fingerprint())
+	})
+
+	t.Run("Changes with either key", func(t *testing.T) {
+		assert.NotEqual(t, fingerprint, PublicKeys{EncKey: []byte{1, 2, 4}, SigKey: pk.SigKey}.Fingerprint())
+		assert.NotEqual(t, fingerprint, PublicKeys{EncKey: pk.EncKey, SigKey: []byte{4, 5, 7}}.Fingerprint())
+	})
+
+	t.Run("Length separator prevents shifting bytes between keys", func(t *testing.T) {
+		shifted := PublicKeys{EncKey: []byte{1, 2}, SigKey: []byte{3, 4, 5, 6}}
+		assert.NotEqual(t, fingerprint, shifted.Fingerprint())
+	})
+
+	t.Run("Zero value", func(t *testing.T) {
+		assert.Equal(t, "", PublicKeys{}.Fingerprint())
+	})
+}
+
+func TestPublicKeys_OpenAPISchema(t *testing.T) {
+	schema, err := PublicKeys{}.OpenAPISchema()
+	require.NoError(t, err)
+
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, map[string]any{
+		"encKey":       map[string]any{"type": "string", "format": "byte"},
+		"sigKey":       map[string]any{"type": "string", "format": "byte"},
+		"encAlgorithm": map[string]any{"type": "string"},
+		"createdAt":    map[string]any{"type": "integer", "format": "int64"},
+		"expiresAt":    map[string]any{"type": "integer", "format": "int64"},
+		"sigAlgorithm": map[string]any{"type": "string"},
+	}, schema["properties"])
+}
+
+func TestPublicKeys_Validate(t *testing.T) {
+	validKeys := func() PublicKeys {
+		return PublicKeys{EncKey: bytes.Repeat([]byte{1}, 32), SigKey: bytes.Repeat([]byte{2}, 32)}
+	}
+
+	t.Run("Correct keys", func(t *testing.T) {
+		assert.NoError(t, validKeys().Validate())
+	})
+
+	t.Run("Missing keys", func(t *testing.T) {
+		pk := validKeys()
+		pk.EncKey = nil
+		err := pk.Validate()
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.ErrorContains(t, err, "encKey is missing")
+
+		pk = validKeys()
+		pk.SigKey = []byte{}
+		assert.ErrorContains(t, pk.Validate(), "sigKey is missing")
+	})
+
+	t.Run("Short keys", func(t *testing.T) {
+		pk := validKeys()
+		pk.EncKey = pk.EncKey[:16]
+		err := pk.Validate()
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.ErrorContains(t, err, "encKey is 16 bytes, expected 32")
+
+		pk = validKeys()
+		pk.SigKey = pk.SigKey[:31]
+		assert.ErrorContains(t, pk.Validate(), "sigKey is 31 bytes")
+	})
+
+	t.Run("Overridden lengths", func(t *testing.T) {
+		pk := PublicKeys{EncKey: bytes.Repeat([]byte{1}, 65), SigKey: bytes.Repeat([]byte{2}, 32)}
+		assert.ErrorIs(t, pk.Validate(), ErrInvalidKey)
+		assert.NoError(t, pk.ValidateLengths(KeyLengths{EncKey: 65, SigKey: 32}))
+		assert.NoError(t, pk.ValidateLengths(KeyLengths{}))
+	})
+}
+
+func TestPublicKeys_Algorithms_RoundTrip(t *testing.T) {
+	nativeStruct := &PublicKeys{
+		EncKey:       []byte{1, 2, 3},
+		SigKey:       []byte{4, 5, 6},
+		EncAlgorithm: AlgorithmECDHP256,
+		SigAlgorithm: AlgorithmECDSAP256,
+	}
+
+	t.Run("JSON carries explicit algorithms", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(nativeStruct)
+		require.NoError(t, err)
+
+		var resultStruct PublicKeys
+		require.NoError(t, json.Unmarshal(jsonBytes, &resultStruct))
+		assert.Equal(t, nativeStruct, &resultStruct)
+	})
+
+	t.Run("FromProto defaults legacy messages", func(t *testing.T) {
+		native, err := FromProto(ToProto(nativeStruct))
+		require.NoError(t, err)
+		assert.Equal(t, DefaultEncAlgorithm, native.EncAlgorithm)
+		assert.Equal(t, DefaultSigAlgorithm, native.SigAlgorithm)
+		assert.Equal(t, nativeStruct.EncKey, native.EncKey)
+	})
+
+	t.Run("Legacy JSON defaults", func(t *testing.T) {
+		var resultStruct PublicKeys
+		require.NoError(t, json.Unmarshal([]byte(`{"encKey":"AQID"}`), &resultStruct))
+		assert.Equal(t, AlgorithmX25519, resultStruct.EncAlgorithm)
+		assert.Equal(t, AlgorithmEd25519, resultStruct.SigAlgorithm)
+	})
+}
+
+func TestPublicKeys_Equal(t *testing.T) {
+	pk := PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+
+	t.Run("Equal", func(t *testing.T) {
+		assert.True(t, pk.Equal(PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}))
+	})
+
+	t.Run("Different enc key", func(t *testing.T) {
+		assert.False(t, pk.Equal(PublicKeys{EncKey: []byte{1, 2, 4}, SigKey: []byte{4, 5, 6}}))
+	})
+
+	t.Run("Different sig key", func(t *testing.T) {
+		assert.False(t, pk.Equal(PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5}}))
+	})
+
+	t.Run("Nil vs empty", func(t *testing.T) {
+		assert.True(t, PublicKeys{EncKey: nil, SigKey: []byte{}}.Equal(PublicKeys{EncKey: []byte{}, SigKey: nil}))
+		assert.False(t, PublicKeys{}.Equal(pk))
+	})
+}
+
+func TestPublicKeys_YAML_RoundTrip(t *testing.T) {
+	original := PublicKeys{
+		EncKey:       []byte{1, 2, 3},
+		SigKey:       []byte{4, 5, 6},
+		EncAlgorithm: AlgorithmX448,
+		SigAlgorithm: AlgorithmEd25519,
+	}
+
+	out, err := yaml.Marshal(original)
+	require.NoError(t, err)
+	assert.Equal(t, "encAlgorithm: X448\nencKey: AQID\nsigAlgorithm: Ed25519\nsigKey: BAUG\n", string(out))
+
+	var got PublicKeys
+	require.NoError(t, yaml.Unmarshal(out, &got))
+	assert.Equal(t, original, got)
+}
+
+func TestSafetyNumber(t *testing.T) {
+	alice := PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+	bob := PublicKeys{EncKey: []byte{7, 8, 9}, SigKey: []byte{10, 11, 12}}
+	number := SafetyNumber(alice, bob)
+
+	t.Run("Symmetric", func(t *testing.T) {
+		assert.Equal(t, number, SafetyNumber(bob, alice))
+	})
+
+	t.Run("Stable", func(t *testing.T) {
+		assert.Equal(t, number, SafetyNumber(alice, bob))
+		assert.Equal(t, "47551 16936 43357 24133 46636 29951 40652 90601 53597 91412 15652 47155", number)
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		groups := strings.Split(number, " ")
+		require.Len(t, groups, 12)
+		for _, group := range groups {
+			assert.Regexp(t, `^[0-9]{5}$`, group)
+		}
+	})
+
+	t.Run("Changes with either key", func(t *testing.T) {
+		rotated := alice
+		rotated.SigKey = []byte{4, 5, 7}
+		assert.NotEqual(t, number, SafetyNumber(rotated, bob))
+		assert.NotEqual(t, number, SafetyNumber(alice, rotated))
+	})
+}
+
+func TestPublicKeys_Expiry(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	created := now.Add(-30 * 24 * time.Hour).UnixMilli()
+
+	testCases := []struct {
+		name      string
+		expiresAt int64
+		expired   bool
+	}{
+		{"Expired", now.Add(-time.Second).UnixMilli(), true},
+		{"Expires now", now.UnixMilli(), true},
+		{"Live", now.Add(time.Hour).UnixMilli(), false},
+		{"Unset", 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pk := PublicKeys{EncKey: []byte{1}, SigKey: []byte{2}, CreatedAt: created, ExpiresAt: tc.expiresAt}
+			assert.Equal(t, tc.expired, pk.IsExpired(now))
+
+			data, err := json.Marshal(pk)
+			require.NoError(t, err)
+			var got PublicKeys
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, created, got.CreatedAt)
+			assert.Equal(t, tc.expiresAt, got.ExpiresAt)
+		})
+	}
+
+	t.Run("Legacy key set", func(t *testing.T) {
+		var legacy PublicKeys
+		require.NoError(t, json.Unmarshal([]byte(`{"encKey":"AQ==","sigKey":"Ag=="}`), &legacy))
+		assert.Zero(t, legacy.CreatedAt)
+		assert.Zero(t, legacy.ExpiresAt)
+		assert.False(t, legacy.IsExpired(now))
+	})
+}
+
+func TestKeyList_RoundTrip(t *testing.T) {
+	list := &KeyList{Keys: []*PublicKeys{
+		{EncKey: []byte{1}, SigKey: []byte{2}, EncAlgorithm: DefaultEncAlgorithm, SigAlgorithm: DefaultSigAlgorithm},
+		{EncKey: []byte{3}, SigKey: []byte{4}, EncAlgorithm: AlgorithmX448, SigAlgorithm: AlgorithmEd448, ExpiresAt: 1000},
+		{EncKey: []byte{5}, SigKey: []byte{6}, EncAlgorithm: DefaultEncAlgorithm, SigAlgorithm: DefaultSigAlgorithm},
+	}}
+
+	t.Run("Proto", func(t *testing.T) {
+		protoList := ListToProto(list)
+		require.Len(t, protoList, 3)
+		assert.Equal(t, []byte{3}, protoList[1].EncKey)
+
+		got, err := ListFromProto(protoList)
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 3)
+		for i := range list.Keys {
+			assert.True(t, list.Keys[i].Equal(*got.Keys[i]), "index %d", i)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(list)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"encAlgorithm":"X448"`)
+
+		var got KeyList
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, list, &got)
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		assert.Nil(t, ListToProto(nil))
+		got, err := ListFromProto(nil)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestPublicKeys_StringAccessors(t *testing.T) {
+	pk := PublicKeys{EncKey: []byte{0xfb, 0xff, 0x01}, SigKey: []byte{0x00, 0x10}}
+
+	assert.Equal(t, "+/8B", pk.EncKeyBase64())
+	assert.Equal(t, "ABA=", pk.SigKeyBase64())
+	assert.Equal(t, "fbff01", pk.EncKeyHex())
+	assert.Equal(t, "0010", pk.SigKeyHex())
+
+	t.Run("Base64 round trip", func(t *testing.T) {
+		var got PublicKeys
+		require.NoError(t, got.SetEncKeyBase64(pk.EncKeyBase64()))
+		require.NoError(t, got.SetSigKeyBase64(pk.SigKeyBase64()))
+		assert.True(t, pk.Equal(got))
+	})
+
+	t.Run("URL-safe and unpadded", func(t *testing.T) {
+		var got PublicKeys
+		require.NoError(t, got.SetEncKeyBase64("-_8B"))
+		require.NoError(t, got.SetSigKeyBase64("ABA"))
+		assert.True(t, pk.Equal(got))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var empty PublicKeys
+		assert.Equal(t, "", empty.EncKeyBase64())
+		assert.Equal(t, "", empty.SigKeyHex())
+
+		got := pk
+		require.NoError(t, got.SetEncKeyBase64(""))
+		assert.Nil(t, got.EncKey)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		got := pk
+		err := got.SetSigKeyBase64("not base64!")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.ErrorContains(t, err, "sigKey")
+		assert.Equal(t, pk.SigKey, got.SigKey)
+	})
+}
+
+func TestPublicKeys_AbsentKeysAreNil(t *testing.T) {
+	for name, input := range map[string]string{
+		"Missing":    `{"sigKey":"AQ=="}`,
+		"Empty":      `{"encKey":"","sigKey":"AQ=="}`,
+		"Null":       `{"encKey":null,"sigKey":"AQ=="}`,
+		"Both empty": `{"encKey":"","sigKey":""}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var pk PublicKeys
+			require.NoError(t, json.Unmarshal([]byte(input), &pk))
+			assert.Nil(t, pk.EncKey)
+			assert.NotEqual(t, []byte{}, pk.EncKey)
+		})
+	}
+
+	t.Run("FromProto", func(t *testing.T) {
+		pk, err := FromProto(&keysv1.PublicKeysPb{EncKey: []byte{}, SigKey: nil})
+		require.NoError(t, err)
+		assert.Nil(t, pk.EncKey)
+		assert.Nil(t, pk.SigKey)
+	})
+
+	t.Run("Missing and empty decode identically", func(t *testing.T) {
+		var missing, empty PublicKeys
+		require.NoError(t, json.Unmarshal([]byte(`{"sigKey":"AQ=="}`), &missing))
+		require.NoError(t, json.Unmarshal([]byte(`{"encKey":"","sigKey":"AQ=="}`), &empty))
+		assert.Equal(t, missing, empty)
+	})
+}
+
+func TestPublicKeys_JSON_ValueRoundTrip(t *testing.T) {
+	pk := PublicKeys{
+		EncKey:       []byte{1, 2, 3},
+		SigKey:       []byte{4, 5, 6},
+		EncAlgorithm: AlgorithmX25519,
+		SigAlgorithm: AlgorithmEd25519,
+		CreatedAt:    1_700_000_000_000,
+		ExpiresAt:    1_800_000_000_000,
+	}
+
+	testsupport.AssertJSONRoundTrip(t, pk)
+	testsupport.AssertJSONRoundTrip(t, KeyList{Keys: []*PublicKeys{&pk}})
+	testsupport.AssertJSONRoundTrip(t, VersionedKeys{KeyID: "k1", Keys: pk})
+	testsupport.AssertJSONRoundTrip(t, KeyBundle{Keys: []*VersionedKeys{{KeyID: "k1", Keys: pk}}})
+}
+
+func TestPublicKeys_MarshalJSONWith(t *testing.T) {
+	pk := PublicKeys{EncKey: []byte{1}, EncAlgorithm: AlgorithmX448, SigAlgorithm: AlgorithmEd448, ExpiresAt: 1500}
+	opts := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+	data, err := pk.MarshalJSONWith(opts)
+	require.NoError(t, err)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "X448", m["enc_algorithm"])
+	assert.Equal(t, "Ed448", m["sig_algorithm"])
+	assert.Equal(t, "", m["sig_key"], "empty fields are emitted")
+	assert.Equal(t, float64(0), m["created_at"])
+	assert.Equal(t, float64(1500), m["expires_at"])
+	assert.NotContains(t, m, "encAlgorithm")
+
+	var back PublicKeys
+	require.NoError(t, json.Unmarshal(data, &back))
+	assert.Equal(t, pk, back)
+
+	t.Run("KeyList", func(t *testing.T) {
+		data, err := KeyList{Keys: []*PublicKeys{&pk}}.MarshalJSONWith(opts)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"enc_algorithm":"X448"`)
+	})
+
+	t.Run("KeyBundle", func(t *testing.T) {
+		bundle := KeyBundle{Keys: []*VersionedKeys{{KeyID: "v1", Keys: pk}}}
+		data, err := bundle.MarshalJSONWith(opts)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"key_id":"v1"`)
+		assert.Contains(t, string(data), `"expires_at":1500`)
+
+		var back KeyBundle
+		require.NoError(t, json.Unmarshal(data, &back))
+		assert.Equal(t, bundle, back)
+	})
+}
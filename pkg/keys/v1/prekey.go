@@ -0,0 +1,81 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+// PreKeyLength is the size of an X25519 pre-key.
+const PreKeyLength = 32
+
+// PreKeyBundle is what a client fetches to start an X3DH handshake with a
+// user: their identity key, a medium-term signed pre-key with the identity
+// key's signature over it, and optionally a one-time pre-key.
+//
+// IdentityKey is an Ed25519 public key; the pre-keys are X25519 public keys.
+// gen-platform has no proto message for pre-key bundles, so PreKeyBundle
+// only has a JSON facade, with the keys as standard base64 like the
+// protojson forms elsewhere in this package.
+type PreKeyBundle struct {
+	IdentityKey           []byte `json:"identityKey"`
+	SignedPreKey          []byte `json:"signedPreKey"`
+	SignedPreKeySignature []byte `json:"signedPreKeySignature"`
+	// OneTimePreKey is empty once the user's one-time pre-keys run out; the
+	// handshake then proceeds without one.
+	OneTimePreKey []byte `json:"oneTimePreKey,omitempty"`
+}
+
+// Validate checks the key sizes and that SignedPreKeySignature is the
+// identity key's signature over SignedPreKey. Errors wrap ErrInvalidKey and
+// name the offending field.
+func (b PreKeyBundle) Validate() error {
+	if err := validateKey("identityKey", b.IdentityKey, ed25519.PublicKeySize); err != nil {
+		return err
+	}
+	if err := validateKey("signedPreKey", b.SignedPreKey, PreKeyLength); err != nil {
+		return err
+	}
+	if len(b.OneTimePreKey) > 0 {
+		if err := validateKey("oneTimePreKey", b.OneTimePreKey, PreKeyLength); err != nil {
+			return err
+		}
+	}
+	if !ed25519.Verify(ed25519.PublicKey(b.IdentityKey), b.SignedPreKey, b.SignedPreKeySignature) {
+		return fmt.Errorf("%w: signedPreKeySignature does not verify against identityKey", ErrInvalidKey)
+	}
+	return nil
+}
+
+// OneTimePreKey is a single-use X25519 pre-key, identified so the
+// handshake's initiator can tell the recipient which one it consumed.
+type OneTimePreKey struct {
+	KeyID  string `json:"keyId"`
+	PubKey []byte `json:"pubKey"`
+}
+
+// OneTimePreKeyList is a user's stock of unused one-time pre-keys. Like
+// PreKeyBundle it only has a JSON facade. It is not safe for concurrent use;
+// callers serialize Take with their storage update.
+type OneTimePreKeyList struct {
+	Keys []OneTimePreKey `json:"keys,omitempty"`
+}
+
+// Take removes and returns the oldest key in the list, or false if the list
+// is empty.
+func (l *OneTimePreKeyList) Take() (OneTimePreKey, bool) {
+	if len(l.Keys) == 0 {
+		return OneTimePreKey{}, false
+	}
+	key := l.Keys[0]
+	l.Keys[0] = OneTimePreKey{}
+	l.Keys = l.Keys[1:]
+	if len(l.Keys) == 0 {
+		l.Keys = nil
+	}
+	return key, true
+}
+
+// Len returns the number of unused keys.
+func (l *OneTimePreKeyList) Len() int {
+	return len(l.Keys)
+}
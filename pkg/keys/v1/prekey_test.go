@@ -0,0 +1,127 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPreKeyBundle(t *testing.T) *PreKeyBundle {
+	t.Helper()
+	identityPub, identityPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signedPreKey := bytes.Repeat([]byte{0x11}, PreKeyLength)
+	return &PreKeyBundle{
+		IdentityKey:           identityPub,
+		SignedPreKey:          signedPreKey,
+		SignedPreKeySignature: ed25519.Sign(identityPriv, signedPreKey),
+		OneTimePreKey:         bytes.Repeat([]byte{0x22}, PreKeyLength),
+	}
+}
+
+func TestPreKeyBundle_JSON_RoundTrip(t *testing.T) {
+	bundle := newTestPreKeyBundle(t)
+
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "ERERERERERERERERERERERERERERERERERERERERERE=", m["signedPreKey"])
+
+	var got PreKeyBundle
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, *bundle, got)
+	assert.NoError(t, got.Validate())
+
+	t.Run("Without a one-time pre-key", func(t *testing.T) {
+		bundle.OneTimePreKey = nil
+		data, err := json.Marshal(bundle)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "oneTimePreKey")
+		assert.NoError(t, bundle.Validate())
+	})
+}
+
+func TestPreKeyBundle_Validate(t *testing.T) {
+	t.Run("Valid signature", func(t *testing.T) {
+		bundle := newTestPreKeyBundle(t)
+		assert.NoError(t, bundle.Validate())
+	})
+
+	t.Run("Signed by another identity", func(t *testing.T) {
+		bundle := newTestPreKeyBundle(t)
+		_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		bundle.SignedPreKeySignature = ed25519.Sign(otherPriv, bundle.SignedPreKey)
+		err = bundle.Validate()
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.ErrorContains(t, err, "signedPreKeySignature")
+	})
+
+	t.Run("Swapped pre-key", func(t *testing.T) {
+		bundle := newTestPreKeyBundle(t)
+		bundle.SignedPreKey = bytes.Repeat([]byte{0x33}, PreKeyLength)
+		assert.ErrorIs(t, bundle.Validate(), ErrInvalidKey)
+	})
+
+	t.Run("Bad key sizes", func(t *testing.T) {
+		for field, mutate := range map[string]func(*PreKeyBundle){
+			"identityKey":   func(b *PreKeyBundle) { b.IdentityKey = b.IdentityKey[:16] },
+			"signedPreKey":  func(b *PreKeyBundle) { b.SignedPreKey = nil },
+			"oneTimePreKey": func(b *PreKeyBundle) { b.OneTimePreKey = []byte{1} },
+		} {
+			bundle := newTestPreKeyBundle(t)
+			mutate(bundle)
+			err := bundle.Validate()
+			assert.ErrorIs(t, err, ErrInvalidKey, field)
+			assert.ErrorContains(t, err, field)
+		}
+	})
+}
+
+func TestOneTimePreKeyList(t *testing.T) {
+	list := &OneTimePreKeyList{Keys: []OneTimePreKey{
+		{KeyID: "otk-1", PubKey: bytes.Repeat([]byte{1}, PreKeyLength)},
+		{KeyID: "otk-2", PubKey: bytes.Repeat([]byte{2}, PreKeyLength)},
+		{KeyID: "otk-3", PubKey: bytes.Repeat([]byte{3}, PreKeyLength)},
+	}}
+
+	t.Run("JSON round trip", func(t *testing.T) {
+		data, err := json.Marshal(list)
+		require.NoError(t, err)
+		var got OneTimePreKeyList
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, *list, got)
+	})
+
+	t.Run("Take down to empty", func(t *testing.T) {
+		for i, want := range []string{"otk-1", "otk-2", "otk-3"} {
+			assert.Equal(t, 3-i, list.Len())
+			key, ok := list.Take()
+			require.True(t, ok)
+			assert.Equal(t, want, key.KeyID)
+			assert.Len(t, key.PubKey, PreKeyLength)
+		}
+		assert.Zero(t, list.Len())
+
+		key, ok := list.Take()
+		assert.False(t, ok)
+		assert.Equal(t, OneTimePreKey{}, key)
+
+		data, err := json.Marshal(list)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{}`, string(data))
+	})
+
+	t.Run("Zero value", func(t *testing.T) {
+		var empty OneTimePreKeyList
+		_, ok := empty.Take()
+		assert.False(t, ok)
+		assert.Zero(t, empty.Len())
+	})
+}
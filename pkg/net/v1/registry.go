@@ -0,0 +1,107 @@
+package urn
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+)
+
+// registryTypes maps a namespace to its allowed entity types. A registered
+// namespace with no types is unrestricted.
+type registryTypes map[string]map[string]struct{}
+
+// registry records the namespaces in use and, for namespaces that opt in,
+// the entity types New accepts. It is process-wide; register at init time.
+//
+// The published map is never modified: writers copy it under registryMu and
+// store the copy, so New and Parse read it without locking.
+var (
+	registry   atomic.Pointer[registryTypes]
+	registryMu sync.Mutex
+)
+
+func init() {
+	registry.Store(&registryTypes{
+		SecureMessaging: nil,
+		AuthNamespace:   nil,
+		LookupNamespace: nil,
+	})
+}
+
+// updateRegistry publishes a copy of the registry changed by fn. fn may
+// modify the outer map; it must replace, not modify, an inner one.
+func updateRegistry(fn func(types registryTypes)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	types := maps.Clone(*registry.Load())
+	fn(types)
+	registry.Store(&types)
+}
+
+// RegisterNamespace adds namespace to the list returned by Namespaces without
+// restricting its entity types.
+func RegisterNamespace(namespace string) {
+	updateRegistry(func(types registryTypes) {
+		if _, ok := types[namespace]; !ok {
+			types[namespace] = nil
+		}
+	})
+}
+
+// RegisterEntityType allows entityType in namespace, registering the
+// namespace if needed. Once a namespace has any registered type, New rejects
+// the types that are not registered for it; namespaces without registered
+// types keep accepting any type.
+//
+// Registering types for SecureMessaging restricts legacy bare IDs too, since
+// Parse upgrades them to "urn:sm:user:<id>": include EntityTypeUser.
+func RegisterEntityType(namespace, entityType string) {
+	updateRegistry(func(types registryTypes) {
+		allowed := maps.Clone(types[namespace])
+		if allowed == nil {
+			allowed = map[string]struct{}{}
+		}
+		allowed[entityType] = struct{}{}
+		types[namespace] = allowed
+	})
+}
+
+// Namespaces returns the registered namespaces, sorted. The standard
+// namespaces are always included.
+func Namespaces() []string {
+	return slices.Sorted(maps.Keys(*registry.Load()))
+}
+
+// EntityTypes returns the entity types registered for namespace, sorted, or
+// nil if the namespace accepts any type.
+func EntityTypes(namespace string) []string {
+	types := (*registry.Load())[namespace]
+	if len(types) == 0 {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(types))
+}
+
+// isRegisteredNamespace reports whether namespace is one Namespaces returns.
+func isRegisteredNamespace(namespace string) bool {
+	_, ok := (*registry.Load())[namespace]
+	return ok
+}
+
+// checkEntityType reports an error if namespace restricts its entity types
+// and entityType is not one of them.
+func checkEntityType(namespace, entityType string) error {
+	types := (*registry.Load())[namespace]
+	if len(types) == 0 {
+		return nil
+	}
+	if _, ok := types[entityType]; ok {
+		return nil
+	}
+	return validation.NewFieldError("entityType",
+		fmt.Sprintf("%q is not registered for namespace %q", entityType, namespace), ErrConstraintViolation)
+}
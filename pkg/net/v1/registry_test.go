@@ -0,0 +1,72 @@
+package urn_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+)
+
+func TestNamespaces(t *testing.T) {
+	namespaces := urn.Namespaces()
+	assert.Subset(t, namespaces, []string{urn.SecureMessaging, urn.AuthNamespace, urn.LookupNamespace})
+	assert.IsNonDecreasing(t, namespaces)
+
+	urn.RegisterNamespace("test-enum")
+	assert.Contains(t, urn.Namespaces(), "test-enum")
+	assert.Nil(t, urn.EntityTypes("test-enum"))
+}
+
+func TestRegisterEntityType(t *testing.T) {
+	// Unregistered namespaces accept any type
+	_, err := urn.New("test-open", "anything", "id-1")
+	require.NoError(t, err)
+
+	urn.RegisterEntityType("test-restricted", "widget")
+	urn.RegisterEntityType("test-restricted", "gadget")
+
+	assert.Contains(t, urn.Namespaces(), "test-restricted")
+	assert.Equal(t, []string{"gadget", "widget"}, urn.EntityTypes("test-restricted"))
+
+	u, err := urn.New("test-restricted", "widget", "id-1")
+	require.NoError(t, err)
+	assert.Equal(t, "urn:test-restricted:widget:id-1", u.String())
+
+	_, err = urn.New("test-restricted", "user", "id-1")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, urn.ErrConstraintViolation)
+	var fe *validation.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "entityType", fe.Field())
+
+	// Parse goes through the same check
+	_, err = urn.Parse("urn:test-restricted:user:id-1")
+	assert.ErrorIs(t, err, urn.ErrConstraintViolation)
+
+	// Other namespaces are unaffected
+	_, err = urn.New("test-open", "user", "id-1")
+	assert.NoError(t, err)
+}
+
+func TestRegisterEntityType_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			urn.RegisterEntityType("test-concurrent", fmt.Sprintf("type-%d", i))
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = urn.New("test-concurrent", "type-0", "id")
+			_ = urn.Namespaces()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, urn.EntityTypes("test-concurrent"), 8)
+}
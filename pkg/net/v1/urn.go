@@ -6,9 +6,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
 	"strings"
+	"unicode"
+	"unique"
 
 	netv1 "github.com/tinywideclouds/gen-platform/go/types/net/v1"
+	"github.com/tinywideclouds/go-platform/internal/cborpb"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -20,12 +32,15 @@ const (
 	SecureMessaging = "sm"
 	// AuthNamespace is for federated identities ("auth").
 	AuthNamespace = "auth"
-	// LookupNamespace is for database lookup keys ("lookup").
+	// LookupNamespace is for database lookup keys ("lookup"). Its entity IDs
+	// are opaque external keys: see New.
 	LookupNamespace = "lookup"
 
 	urnParts     = 4
 	urnDelimiter = ":"
 
+	entityPathSeparator = "/"
+
 	// EntityTypeUser is a standard entity type for users.
 	EntityTypeUser = "user"
 	// EntityTypeGroup is a standard entity type for groups.
@@ -36,9 +51,22 @@ var (
 	// ErrInvalidFormat is returned when a string or components do not conform
 	// to the expected URN structure.
 	ErrInvalidFormat = errors.New("invalid URN format")
+
+	// ErrConstraintViolation is returned when a URN does not satisfy a URNConstraint.
+	ErrConstraintViolation = errors.New("URN violates constraint")
 )
 
+// MaxURNLength is the longest string, in bytes, Parse will attempt to parse.
+// Longer input is rejected with ErrInvalidFormat before any splitting, which
+// caps the work done on hostile input. Set it at startup, not concurrently
+// with parsing.
+var MaxURNLength = 1024
+
 // URN represents a parsed, validated Uniform Resource Name.
+//
+// URN is comparable and every constructor (New, Parse, FromProto) normalizes
+// to the same struct, so equal URNs compare with == and can be used directly
+// as map keys (map[URN]V) without the allocation of a String() key.
 type URN struct {
 	scheme     string
 	namespace  string
@@ -48,9 +76,27 @@ type URN struct {
 
 // New is the constructor for a URN.
 // REFACTOR: Removed namespace validation. This is now a general-purpose URN container.
+//
+// An empty part is reported as a *validation.FieldError naming the part and
+// wrapping ErrInvalidFormat. If entity types have been registered for the
+// namespace (see RegisterEntityType), an unregistered type is a FieldError
+// wrapping ErrConstraintViolation.
+//
+// The entity ID of a LookupNamespace URN stores third-party identifiers
+// verbatim: when parsed it may contain delimiters ("urn:lookup:ext:a b:c"
+// has entity ID "a b:c").
 func New(namespace, entityType, entityID string) (URN, error) {
-	if namespace == "" || entityType == "" || entityID == "" {
-		return URN{}, ErrInvalidFormat
+	for _, part := range []struct{ field, value string }{
+		{"namespace", namespace},
+		{"entityType", entityType},
+		{"entityId", entityID},
+	} {
+		if part.value == "" {
+			return URN{}, validation.NewFieldError(part.field, "must not be empty", ErrInvalidFormat)
+		}
+	}
+	if err := checkEntityType(namespace, entityType); err != nil {
+		return URN{}, err
 	}
 
 	return URN{
@@ -61,20 +107,85 @@ func New(namespace, entityType, entityID string) (URN, error) {
 	}, nil
 }
 
+// checkChars rejects a part of u containing whitespace or control
+// characters, except that a LookupNamespace entity ID may contain
+// whitespace.
+func checkChars(u URN) error {
+	for _, part := range []struct{ field, value string }{
+		{"namespace", u.namespace},
+		{"entityType", u.entityType},
+		{"entityId", u.entityID},
+	} {
+		if part.field == "entityId" && isOpaqueNamespace(u.namespace) {
+			if strings.IndexFunc(part.value, unicode.IsControl) >= 0 {
+				return validation.NewFieldError(part.field, "must not contain control characters", ErrInvalidFormat)
+			}
+			continue
+		}
+		if strings.IndexFunc(part.value, invalidPartRune) >= 0 {
+			return validation.NewFieldError(part.field, "must not contain whitespace or control characters", ErrInvalidFormat)
+		}
+	}
+	return nil
+}
+
+// isEntityPath reports whether id is a sub-entity path whose delimiters all
+// fall after the root ID, i.e. the first delimiter comes after a "/".
+func isEntityPath(id string) bool {
+	slash := strings.Index(id, entityPathSeparator)
+	return slash > 0 && slash < strings.Index(id, urnDelimiter)
+}
+
+// isOpaqueNamespace reports whether namespace's entity IDs are opaque keys
+// exempt from the entity ID delimiter and whitespace checks.
+func isOpaqueNamespace(namespace string) bool {
+	return namespace == LookupNamespace
+}
+
+// invalidPartRune reports the characters ParseLenient rejects in a URN part.
+func invalidPartRune(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
 // Parse converts a URN string into a validated URN struct.
+//
+// Everything after the third delimiter is the entity ID. It may only contain
+// further delimiters as a sub-entity path (see SubEntities), so
+// "urn:sm:thread:t1/message:m2" parses but "urn:sm:user:a:b" does not. A
+// LookupNamespace entity ID may contain any delimiters (see New).
+//
+// Parse is safe on arbitrary untrusted input: it never panics, and it
+// returns either an error with the zero URN or a URN whose String form
+// parses back to the same value. FuzzParse checks both.
 func Parse(s string) (URN, error) {
+	if len(s) > MaxURNLength {
+		return URN{}, fmt.Errorf("%w: length %d exceeds the maximum of %d", ErrInvalidFormat, len(s), MaxURNLength)
+	}
+
 	// Handle empty string as a zero-value URN
 	if s == "" {
 		return URN{}, nil
 	}
 
-	parts := strings.Split(s, urnDelimiter)
+	parts := strings.SplitN(s, urnDelimiter, urnParts)
+	if len(parts) == urnParts && strings.Contains(parts[3], urnDelimiter) && !isEntityPath(parts[3]) && !isOpaqueNamespace(parts[1]) {
+		return URN{}, fmt.Errorf("%w: expected %d parts, got %d", ErrInvalidFormat, urnParts, strings.Count(s, urnDelimiter)+1)
+	}
 	if len(parts) != urnParts {
 		// --- Backward Compatibility for Legacy UserIDs ---
 		// If only one part (e.g. "user-123"), auto-upgrade to urn:sm:user:user-123
 		// We default to 'sm' for legacy support, but new URNs can be anything.
+		// ParseURNOrUserID reports when this happens.
 		if len(parts) == 1 {
-			return New(SecureMessaging, EntityTypeUser, s)
+			u, err := New(SecureMessaging, EntityTypeUser, s)
+			if err != nil {
+				return URN{}, err
+			}
+			// The upgraded form must itself be parseable.
+			if n := len(u.String()); n > MaxURNLength {
+				return URN{}, fmt.Errorf("%w: length %d exceeds the maximum of %d", ErrInvalidFormat, n, MaxURNLength)
+			}
+			return u, nil
 		}
 		return URN{}, fmt.Errorf("%w: expected %d parts, got %d", ErrInvalidFormat, urnParts, len(parts))
 	}
@@ -87,6 +198,195 @@ func Parse(s string) (URN, error) {
 	return New(parts[1], parts[2], parts[3])
 }
 
+// ParseURNOrUserID is Parse that also reports whether s was a bare legacy
+// user ID ("user-123") promoted to "urn:sm:user:user-123" rather than a full
+// URN, so callers can log or reject the legacy form deliberately. The flag is
+// false on error and for the empty string.
+func ParseURNOrUserID(s string) (u URN, promoted bool, err error) {
+	u, err = Parse(s)
+	if err != nil {
+		return URN{}, false, err
+	}
+	return u, s != "" && !strings.Contains(s, urnDelimiter), nil
+}
+
+// Intern returns u with its parts replaced by canonical copies from a
+// process-wide, concurrency-safe intern table (the standard unique package),
+// so every interned URN with the same value shares one set of strings.
+//
+// A URN from Parse keeps the whole input string alive; interning lets that
+// input be collected and stops thousands of copies of a fan-out recipient
+// from each holding their own. The cost is a hash lookup per part, so use it
+// where the same URNs repeat heavily and are retained, not on every parse.
+//
+// Sharing is best effort. Intern keeps no unique.Handle, and unique drops a
+// canonical copy once no handle to it is reachable, so the table never
+// outgrows the live handles but a URN interned after a garbage collection
+// may get a fresh copy rather than the one earlier URNs hold.
+func Intern(u URN) URN {
+	if u.IsZero() {
+		return u
+	}
+	return URN{
+		scheme:     unique.Make(u.scheme).Value(),
+		namespace:  unique.Make(u.namespace).Value(),
+		entityType: unique.Make(u.entityType).Value(),
+		entityID:   unique.Make(u.entityID).Value(),
+	}
+}
+
+// ParseInterned is Parse followed by Intern.
+func ParseInterned(s string) (URN, error) {
+	u, err := Parse(s)
+	if err != nil {
+		return URN{}, err
+	}
+	return Intern(u), nil
+}
+
+// NormalizeScheme returns s with a canonical "urn" scheme. A mis-cased
+// scheme ("URN:sm:user:x") is lowercased, and a string that starts with a
+// registered namespace ("sm:user:x", see Namespaces) gets the scheme
+// prepended. Bare legacy IDs without a delimiter are returned unchanged. Any
+// other leading segment (e.g. "http") is rejected.
+func NormalizeScheme(s string) (string, error) {
+	first, rest, found := strings.Cut(s, urnDelimiter)
+	if !found {
+		return s, nil
+	}
+	switch {
+	case strings.EqualFold(first, Scheme):
+		return Scheme + urnDelimiter + rest, nil
+	case isRegisteredNamespace(first):
+		return Scheme + urnDelimiter + s, nil
+	}
+	return "", fmt.Errorf("%w: invalid scheme: expected 'urn', got '%s'", ErrInvalidFormat, first)
+}
+
+// ParseLenient is Parse for input from CSV files, forms and systems that omit
+// or mis-case the scheme. It trims surrounding whitespace and applies
+// NormalizeScheme before parsing. Whitespace and control characters inside
+// a part are rejected, except whitespace in a LookupNamespace entity ID.
+// Parse and New do not check characters, so stored URNs such as
+// "urn:sm:user:john doe" still decode.
+func ParseLenient(s string) (URN, error) {
+	normalized, err := NormalizeScheme(strings.TrimSpace(s))
+	if err != nil {
+		return URN{}, err
+	}
+	u, err := Parse(normalized)
+	if err != nil || u.IsZero() {
+		return u, err
+	}
+	if err := checkChars(u); err != nil {
+		return URN{}, err
+	}
+	return u, nil
+}
+
+// Canonical returns u with the namespace and entity type lowercased, so URNs
+// from producers that disagree on their casing ("urn:SM:User:x") compare
+// equal. The entity ID is case-sensitive and kept as is. New does not
+// canonicalize; call Canonical at the boundary where mixed input arrives.
+func (u URN) Canonical() URN {
+	if u.IsZero() {
+		return u
+	}
+	u.namespace = strings.ToLower(u.namespace)
+	u.entityType = strings.ToLower(u.entityType)
+	return u
+}
+
+// FromPathSegment parses a URN taken from a URL path segment, undoing the
+// percent-encoding clients apply (e.g. "urn%3Asm%3Auser%3Ax").
+func FromPathSegment(s string) (URN, error) {
+	decoded, err := url.PathUnescape(s)
+	if err != nil {
+		return URN{}, fmt.Errorf("%w: invalid path segment: %w", ErrInvalidFormat, err)
+	}
+	return Parse(decoded)
+}
+
+// ToPathSegment returns the String form of u escaped with url.PathEscape,
+// safe to embed as a single URL path segment. FromPathSegment reverses it.
+func (u URN) ToPathSegment() string {
+	return url.PathEscape(u.String())
+}
+
+// AppendQuery adds u to v under key, for list filters such as
+// "?recipient=urn:sm:user:x". v.Encode percent-encodes the colons. The zero
+// URN is not added, so FromQuery reads it back as zero.
+func (u URN) AppendQuery(v url.Values, key string) {
+	if u.IsZero() {
+		return
+	}
+	v.Add(key, u.String())
+}
+
+// FromQuery parses the URN stored under key in v, as decoded by
+// url.ParseQuery. An absent or empty key gives the zero URN and no error; a
+// malformed value gives a *validation.FieldError naming key.
+func FromQuery(v url.Values, key string) (URN, error) {
+	s := v.Get(key)
+	if s == "" {
+		return URN{}, nil
+	}
+	u, err := Parse(s)
+	if err != nil {
+		return URN{}, validation.NewFieldError(key, "failed to parse URN", err)
+	}
+	return u, nil
+}
+
+// urnListEscaper escapes the list separator in JoinURNs. A lookup entity ID
+// may contain a comma; "%" is escaped too so SplitURNs can undo it exactly.
+var urnListEscaper = strings.NewReplacer("%", "%25", ",", "%2C")
+
+// zeroURNElement is the JoinURNs element for the zero URN. A non-zero URN
+// never encodes to it, since JoinURNs escapes "%".
+const zeroURNElement = "%00"
+
+// JoinURNs encodes urns as one comma-separated string of their String forms,
+// for a single HTTP header or environment variable. SplitURNs reverses it,
+// including for a list holding only the zero URN: a zero URN is written as
+// "%00", so it is distinct from the empty list.
+func JoinURNs(urns []URN) string {
+	parts := make([]string, len(urns))
+	for i, u := range urns {
+		if u.IsZero() {
+			parts[i] = zeroURNElement
+			continue
+		}
+		parts[i] = urnListEscaper.Replace(u.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// SplitURNs parses a string from JoinURNs. Empty input gives an empty slice
+// and a "%00" element gives the zero URN, as does an empty element, which
+// older encoders wrote for it. A malformed element is reported with its
+// index, wrapping ErrInvalidFormat.
+func SplitURNs(s string) ([]URN, error) {
+	if s == "" {
+		return []URN{}, nil
+	}
+	parts := strings.Split(s, ",")
+	urns := make([]URN, len(parts))
+	for i, part := range parts {
+		if part == zeroURNElement {
+			continue
+		}
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: element %d: bad escape: %w", ErrInvalidFormat, i, err)
+		}
+		if urns[i], err = Parse(unescaped); err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	return urns, nil
+}
+
 // String implements the fmt.Stringer interface.
 func (u URN) String() string {
 	if u.IsZero() {
@@ -95,8 +395,20 @@ func (u URN) String() string {
 	return fmt.Sprintf("%s:%s:%s:%s", u.scheme, u.namespace, u.entityType, u.entityID)
 }
 
+// LogValue implements slog.LogValuer, logging u as its String form rather
+// than as an opaque struct.
+func (u URN) LogValue() slog.Value {
+	return slog.StringValue(u.String())
+}
+
 // --- Getters ---
 
+// Scheme returns the URN's scheme, always Scheme for a non-zero URN and ""
+// for the zero URN.
+func (u URN) Scheme() string {
+	return u.scheme
+}
+
 func (u URN) Namespace() string {
 	return u.namespace
 }
@@ -109,6 +421,17 @@ func (u URN) EntityID() string {
 	return u.entityID
 }
 
+// SubEntities splits a path-style entity ID such as "t1/message:m2" (from
+// "urn:sm:thread:t1/message:m2") on "/", giving the root ID followed by each
+// nested "type:id" segment. A flat ID gives a single element and the zero
+// URN gives nil.
+func (u URN) SubEntities() []string {
+	if u.entityID == "" {
+		return nil
+	}
+	return strings.Split(u.entityID, entityPathSeparator)
+}
+
 func (u URN) IsZero() bool {
 	return u.scheme == "" && u.namespace == "" && u.entityType == "" && u.entityID == ""
 }
@@ -141,6 +464,96 @@ func (u *URN) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// --- YAML Methods ---
+
+// MarshalYAML implements the yaml.Marshaler interface. Like MarshalJSON, the
+// zero URN is null.
+func (u URN) MarshalYAML() (any, error) {
+	if u.IsZero() {
+		return nil, nil
+	}
+	return u.String(), nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface. Null and the empty
+// string give the zero URN.
+func (u *URN) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return fmt.Errorf("URN should be a string: %w", err)
+	}
+	if s == "" {
+		*u = URN{}
+		return nil
+	}
+	parsedURN, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*u = parsedURN
+	return nil
+}
+
+// --- Msgpack Methods ---
+
+// MarshalMsgpack implements the msgpack.Marshaler interface. The URN is a
+// msgpack string, and the zero URN is nil, as with JSON.
+func (u URN) MarshalMsgpack() ([]byte, error) {
+	if u.IsZero() {
+		return msgpack.Marshal(nil)
+	}
+	return msgpack.Marshal(u.String())
+}
+
+// UnmarshalMsgpack implements the msgpack.Unmarshaler interface. Nil and the
+// empty string give the zero URN.
+func (u *URN) UnmarshalMsgpack(data []byte) error {
+	var s string
+	if err := msgpack.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("URN should be a string: %w", err)
+	}
+	if s == "" {
+		*u = URN{}
+		return nil
+	}
+	parsedURN, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*u = parsedURN
+	return nil
+}
+
+// --- CBOR Methods ---
+
+// MarshalCBOR implements the cbor.Marshaler interface. The URN is a CBOR text
+// string, and the zero URN is null, as with JSON.
+func (u URN) MarshalCBOR() ([]byte, error) {
+	if u.IsZero() {
+		return cborpb.Marshal(nil)
+	}
+	return cborpb.Marshal(u.String())
+}
+
+// UnmarshalCBOR implements the cbor.Unmarshaler interface. Null and the empty
+// string give the zero URN.
+func (u *URN) UnmarshalCBOR(data []byte) error {
+	var s string
+	if err := cborpb.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("URN should be a string: %w", err)
+	}
+	if s == "" {
+		*u = URN{}
+		return nil
+	}
+	parsedURN, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*u = parsedURN
+	return nil
+}
+
 // --- Proto Methods ---
 
 func ToProto(native URN) *netv1.UrnPb {
@@ -154,6 +567,10 @@ func ToProto(native URN) *netv1.UrnPb {
 	}
 }
 
+// FromProto converts a UrnPb to a URN. UrnPb carries no scheme, so the
+// result always has scheme Scheme (the zero URN for a nil proto). Use
+// FromProtoStrict where a differently-schemed proto must not be silently
+// re-schemed.
 func FromProto(proto *netv1.UrnPb) (URN, error) {
 	if proto == nil {
 		return URN{}, nil
@@ -164,3 +581,144 @@ func FromProto(proto *netv1.UrnPb) (URN, error) {
 	}
 	return native, nil
 }
+
+// schemeField is the UrnPb field FromProtoStrict checks once the proto has it.
+const schemeField protoreflect.Name = "scheme"
+
+// FromProtoStrict is FromProto that also rejects a proto whose scheme is not
+// Scheme. Today's UrnPb has no scheme field and this behaves exactly like
+// FromProto; the check is looked up by name so it takes effect, without a
+// code change here, as soon as the generated type gains a "scheme" string
+// field. An empty scheme is taken to mean Scheme.
+func FromProtoStrict(proto *netv1.UrnPb) (URN, error) {
+	if proto == nil {
+		return URN{}, nil
+	}
+	m := proto.ProtoReflect()
+	if fd := m.Descriptor().Fields().ByName(schemeField); fd != nil && fd.Kind() == protoreflect.StringKind {
+		if scheme := m.Get(fd).String(); scheme != "" && scheme != Scheme {
+			return URN{}, validation.NewFieldError("scheme", fmt.Sprintf("expected %q, got %q", Scheme, scheme), ErrInvalidFormat)
+		}
+	}
+	return FromProto(proto)
+}
+
+// FromFields builds a URN from components carried as separate fields, as in
+// proto messages that predate UrnPb. It is equivalent to New; the name marks
+// the call site as reassembling a URN rather than minting one.
+func FromFields(namespace, entityType, entityID string) (URN, error) {
+	return New(namespace, entityType, entityID)
+}
+
+// FromMessageFields reads the named string fields of m via protoreflect and
+// builds a URN from them. Fields are looked up by proto name, then by JSON name.
+func FromMessageFields(m proto.Message, nsField, typeField, idField string) (URN, error) {
+	if m == nil {
+		return URN{}, fmt.Errorf("%w: nil message", ErrInvalidFormat)
+	}
+	msg := m.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+
+	var values [3]string
+	for i, name := range [3]string{nsField, typeField, idField} {
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+			return URN{}, fmt.Errorf("%w: message %s has no string field %q",
+				ErrInvalidFormat, msg.Descriptor().FullName(), name)
+		}
+		values[i] = msg.Get(fd).String()
+	}
+	return FromFields(values[0], values[1], values[2])
+}
+
+// --- Constraints ---
+
+// URNConstraint is a declarative policy for URNs, meant to be loaded from
+// config. Empty fields impose no restriction.
+type URNConstraint struct {
+	// Namespaces lists the allowed namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// EntityTypes lists the allowed entity types.
+	EntityTypes []string `json:"entityTypes,omitempty"`
+	// IDPattern is a regexp the entity ID must match. Anchor it to match the
+	// whole ID.
+	IDPattern string `json:"idPattern,omitempty"`
+	// MinIDLength and MaxIDLength bound the entity ID length in bytes.
+	MinIDLength int `json:"minIdLength,omitempty"`
+	MaxIDLength int `json:"maxIdLength,omitempty"`
+
+	// idRegexp is IDPattern compiled by UnmarshalJSON.
+	idRegexp *regexp.Regexp
+}
+
+// UnmarshalJSON decodes the constraint and checks that IDPattern compiles,
+// so a bad policy fails when the config is loaded rather than on first use.
+func (c *URNConstraint) UnmarshalJSON(data []byte) error {
+	type plain URNConstraint
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	re, err := regexp.Compile(decoded.IDPattern)
+	if err != nil {
+		return fmt.Errorf("invalid idPattern: %w", err)
+	}
+	*c = URNConstraint(decoded)
+	c.idRegexp = re
+	return nil
+}
+
+// Validate reports the first rule of c that u breaks, wrapping
+// ErrConstraintViolation. It reuses the IDPattern compiled by UnmarshalJSON,
+// and compiles it on each call for a constraint built in code.
+func (c URNConstraint) Validate(u URN) error {
+	if len(c.Namespaces) > 0 && !slices.Contains(c.Namespaces, u.namespace) {
+		return fmt.Errorf("%w: namespace %q is not allowed", ErrConstraintViolation, u.namespace)
+	}
+	if len(c.EntityTypes) > 0 && !slices.Contains(c.EntityTypes, u.entityType) {
+		return fmt.Errorf("%w: entity type %q is not allowed", ErrConstraintViolation, u.entityType)
+	}
+	if c.MinIDLength > 0 && len(u.entityID) < c.MinIDLength {
+		return fmt.Errorf("%w: entity ID shorter than %d", ErrConstraintViolation, c.MinIDLength)
+	}
+	if c.MaxIDLength > 0 && len(u.entityID) > c.MaxIDLength {
+		return fmt.Errorf("%w: entity ID longer than %d", ErrConstraintViolation, c.MaxIDLength)
+	}
+	if c.IDPattern != "" {
+		re := c.idRegexp
+		if re == nil || re.String() != c.IDPattern {
+			var err error
+			if re, err = regexp.Compile(c.IDPattern); err != nil {
+				return fmt.Errorf("invalid idPattern: %w", err)
+			}
+		}
+		if !re.MatchString(u.entityID) {
+			return fmt.Errorf("%w: entity ID %q does not match %q", ErrConstraintViolation, u.entityID, c.IDPattern)
+		}
+	}
+	return nil
+}
+
+// In reports whether u's namespace is one of namespaces. The zero URN is in
+// none.
+func (u URN) In(namespaces ...string) bool {
+	return !u.IsZero() && slices.Contains(namespaces, u.namespace)
+}
+
+// ValidateRecipient is the inbound recipient check for gateways: u must be
+// non-zero, with its namespace in allowedNamespaces and its entity type in
+// allowedTypes. As with URNConstraint, an empty list allows any value.
+// Failures wrap ErrConstraintViolation and name the rejected URN.
+func ValidateRecipient(u URN, allowedNamespaces []string, allowedTypes []string) error {
+	if u.IsZero() {
+		return fmt.Errorf("%w: recipient is required", ErrConstraintViolation)
+	}
+	c := URNConstraint{Namespaces: allowedNamespaces, EntityTypes: allowedTypes}
+	if err := c.Validate(u); err != nil {
+		return fmt.Errorf("recipient %s: %w", u, err)
+	}
+	return nil
+}
@@ -9,12 +9,25 @@
 package urn_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"log/slog"
+	"net/url"
+	"runtime"
+	"strings"
 	"testing"
+	"unique"
+	"unsafe"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	netv1 "github.com/tinywideclouds/gen-platform/go/types/net/v1"
+	userv1 "github.com/tinywideclouds/gen-platform/go/types/user/v1"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
 )
 
 // TestNewURN validates the behavior of the new constructor function.
@@ -56,6 +69,13 @@ func TestNewURN(t *testing.T) {
 		require.Error(t, err)
 		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
 	})
+
+	t.Run("Empty part is a FieldError", func(t *testing.T) {
+		_, err := urn.New(urn.SecureMessaging, "", "user-123")
+		var fe *validation.FieldError
+		require.ErrorAs(t, err, &fe)
+		assert.Equal(t, "entityType", fe.Field())
+	})
 }
 
 func TestParseURN(t *testing.T) {
@@ -86,6 +106,13 @@ func TestParseURN(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid scheme")
 	})
 
+	t.Run("Invalid Format - Too Many Parts", func(t *testing.T) {
+		for _, input := range []string{"urn:sm:user:a:b", "urn:sm:user:a:b/c", "urn:sm:user:/a:b"} {
+			_, err := urn.Parse(input)
+			assert.ErrorIs(t, err, urn.ErrInvalidFormat, input)
+		}
+	})
+
 	t.Run("Invalid Format - Too Few Parts", func(t *testing.T) {
 		_, err := urn.Parse("urn:sm:user")
 		require.Error(t, err)
@@ -105,6 +132,147 @@ func TestStringer(t *testing.T) {
 }
 
 // TestJSONMarshaling verifies the custom JSON marshaler.
+func TestScheme(t *testing.T) {
+	u, err := urn.Parse("urn:sm:user:user-123")
+	require.NoError(t, err)
+	assert.Equal(t, urn.Scheme, u.Scheme())
+
+	assert.Equal(t, "", urn.URN{}.Scheme())
+}
+
+func TestCanonical(t *testing.T) {
+	mixed, err := urn.Parse("urn:SM:User:x")
+	require.NoError(t, err)
+	lower, err := urn.Parse("urn:sm:user:x")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, lower, mixed)
+	assert.Equal(t, lower, mixed.Canonical())
+	assert.Equal(t, lower, lower.Canonical())
+	assert.Equal(t, "urn:SM:User:x", mixed.String(), "Canonical does not modify the receiver")
+
+	caseID, err := urn.Parse("urn:Auth:Google:AbC123")
+	require.NoError(t, err)
+	assert.Equal(t, "urn:auth:google:AbC123", caseID.Canonical().String())
+
+	assert.True(t, urn.URN{}.Canonical().IsZero())
+}
+
+func TestSubEntities(t *testing.T) {
+	testCases := []struct {
+		input    string
+		entityID string
+		expected []string
+	}{
+		{"urn:sm:thread:t1", "t1", []string{"t1"}},
+		{"urn:sm:thread:t1/message:m2", "t1/message:m2", []string{"t1", "message:m2"}},
+		{"urn:sm:thread:t1/message:m2/reaction:r3", "t1/message:m2/reaction:r3", []string{"t1", "message:m2", "reaction:r3"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			u, err := urn.Parse(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, "thread", u.EntityType())
+			assert.Equal(t, tc.entityID, u.EntityID())
+			assert.Equal(t, tc.expected, u.SubEntities())
+			assert.Equal(t, tc.input, u.String())
+
+			data, err := json.Marshal(u)
+			require.NoError(t, err)
+			var roundTrip urn.URN
+			require.NoError(t, json.Unmarshal(data, &roundTrip))
+			assert.Equal(t, u, roundTrip)
+		})
+	}
+
+	assert.Nil(t, urn.URN{}.SubEntities())
+}
+
+func TestPathSegment(t *testing.T) {
+	for _, s := range []string{
+		"urn:sm:user:user-123",
+		"urn:lookup:email:alice+tag@example.com",
+		"urn:sm:thread:t1/message:m2",
+		"urn:auth:google:a?b#c&d=e%20f",
+	} {
+		t.Run(s, func(t *testing.T) {
+			u, err := urn.Parse(s)
+			require.NoError(t, err)
+
+			segment := u.ToPathSegment()
+			assert.NotContains(t, segment, "/")
+			assert.NotContains(t, segment, "?")
+			assert.NotContains(t, segment, "#")
+
+			fromSegment, err := urn.FromPathSegment(segment)
+			require.NoError(t, err)
+			assert.Equal(t, u, fromSegment)
+
+			// Clients that escape the colons too.
+			fromEscaped, err := urn.FromPathSegment(url.QueryEscape(s))
+			require.NoError(t, err)
+			assert.Equal(t, u, fromEscaped)
+
+			unescaped, err := url.PathUnescape(segment)
+			require.NoError(t, err)
+			assert.Equal(t, s, unescaped)
+		})
+	}
+
+	_, err := urn.FromPathSegment("urn%3Asm%3Auser%3Abad%ZZ")
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+
+	assert.Equal(t, "", urn.URN{}.ToPathSegment())
+}
+
+func TestQuery_RoundTrip(t *testing.T) {
+	for _, s := range []string{"urn:sm:user:x", "urn:sm:thread:t1/message:m2", "urn:sm:user:a+b&c=d"} {
+		t.Run(s, func(t *testing.T) {
+			u, err := urn.Parse(s)
+			require.NoError(t, err)
+
+			v := url.Values{}
+			u.AppendQuery(v, "recipient")
+			encoded := v.Encode()
+			assert.NotContains(t, encoded, ":", "colons are percent-encoded")
+
+			decoded, err := url.ParseQuery(encoded)
+			require.NoError(t, err)
+			got, err := urn.FromQuery(decoded, "recipient")
+			require.NoError(t, err)
+			assert.Equal(t, u, got)
+		})
+	}
+
+	t.Run("Unescaped colons are accepted", func(t *testing.T) {
+		v, err := url.ParseQuery("recipient=urn:sm:user:x&limit=10")
+		require.NoError(t, err)
+		got, err := urn.FromQuery(v, "recipient")
+		require.NoError(t, err)
+		assert.Equal(t, "urn:sm:user:x", got.String())
+	})
+
+	t.Run("Absent key gives the zero URN", func(t *testing.T) {
+		got, err := urn.FromQuery(url.Values{"limit": {"10"}}, "recipient")
+		require.NoError(t, err)
+		assert.True(t, got.IsZero())
+	})
+
+	t.Run("Zero URN is not added", func(t *testing.T) {
+		v := url.Values{}
+		urn.URN{}.AppendQuery(v, "recipient")
+		assert.Empty(t, v)
+	})
+
+	t.Run("Malformed value", func(t *testing.T) {
+		_, err := urn.FromQuery(url.Values{"recipient": {"urn:sm:user"}}, "recipient")
+		require.ErrorIs(t, err, urn.ErrInvalidFormat)
+		fe, ok := validation.AsFieldError(err)
+		require.True(t, ok)
+		assert.Equal(t, "recipient", fe.Field())
+	})
+}
+
 func TestJSONMarshaling(t *testing.T) {
 	u, err := urn.New(urn.SecureMessaging, "user", "user-123")
 	require.NoError(t, err)
@@ -234,3 +402,743 @@ func TestURN_Proto_RoundTrip(t *testing.T) {
 	})
 
 }
+
+// TestURN_AsMapKey verifies that equal URNs are struct-identical regardless
+// of how they were constructed, so they can key a map directly.
+func TestFromProto_Scheme(t *testing.T) {
+	for name, fromProto := range map[string]func(*netv1.UrnPb) (urn.URN, error){
+		"FromProto":       urn.FromProto,
+		"FromProtoStrict": urn.FromProtoStrict,
+	} {
+		t.Run(name, func(t *testing.T) {
+			u, err := fromProto(&netv1.UrnPb{Namespace: "sm", EntityType: "user", EntityId: "x"})
+			require.NoError(t, err)
+			assert.Equal(t, urn.Scheme, u.Scheme(), "UrnPb has no scheme field, so the scheme is always assumed")
+
+			u, err = fromProto(nil)
+			require.NoError(t, err)
+			assert.True(t, u.IsZero())
+			assert.Equal(t, "", u.Scheme())
+
+			_, err = fromProto(&netv1.UrnPb{})
+			assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+		})
+	}
+}
+
+func TestURN_AsMapKey(t *testing.T) {
+	constructed, err := urn.New(urn.SecureMessaging, urn.EntityTypeUser, "user-123")
+	require.NoError(t, err)
+	parsed, err := urn.Parse("urn:sm:user:user-123")
+	require.NoError(t, err)
+	legacy, err := urn.Parse("user-123")
+	require.NoError(t, err)
+	fromProto, err := urn.FromProto(urn.ToProto(constructed))
+	require.NoError(t, err)
+
+	assert.True(t, constructed == parsed)
+	assert.True(t, constructed == legacy)
+	assert.True(t, constructed == fromProto)
+
+	counts := map[urn.URN]int{}
+	for _, u := range []urn.URN{constructed, parsed, legacy, fromProto} {
+		counts[u]++
+	}
+	assert.Len(t, counts, 1)
+	assert.Equal(t, 4, counts[constructed])
+
+	other, err := urn.Parse("urn:sm:user:user-456")
+	require.NoError(t, err)
+	counts[other]++
+	assert.Len(t, counts, 2)
+
+	// The zero URN is a valid, distinct key.
+	counts[urn.URN{}]++
+	assert.Len(t, counts, 3)
+}
+
+func TestFromFields(t *testing.T) {
+	u, err := urn.FromFields(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, "urn:sm:user:user-123", u.String())
+
+	_, err = urn.FromFields(urn.SecureMessaging, "", "user-123")
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+}
+
+func TestFromMessageFields(t *testing.T) {
+	t.Run("Reads named fields", func(t *testing.T) {
+		// UserPb has no URN, but its string fields stand in for a legacy message.
+		msg := &userv1.UserPb{Id: "user-123", Alias: "sm", Name: "user"}
+
+		u, err := urn.FromMessageFields(msg, "alias", "name", "id")
+		require.NoError(t, err)
+		assert.Equal(t, "urn:sm:user:user-123", u.String())
+	})
+
+	t.Run("Reads fields by JSON name", func(t *testing.T) {
+		profileURL := "avatar-1"
+		msg := &userv1.UserPb{Alias: "sm", Name: "avatar", ProfileUrl: &profileURL}
+
+		// profile_url is declared with the JSON name profileUrl.
+		u, err := urn.FromMessageFields(msg, "alias", "name", "profileUrl")
+		require.NoError(t, err)
+		assert.Equal(t, "urn:sm:avatar:avatar-1", u.String())
+	})
+
+	t.Run("Matches FromProto on UrnPb", func(t *testing.T) {
+		msg := &netv1.UrnPb{Namespace: "auth", EntityType: "google", EntityId: "123"}
+
+		fromFields, err := urn.FromMessageFields(msg, "namespace", "entityType", "entityId")
+		require.NoError(t, err)
+		fromProto, err := urn.FromProto(msg)
+		require.NoError(t, err)
+		assert.Equal(t, fromProto, fromFields)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		_, err := urn.FromMessageFields(&userv1.UserPb{}, "alias", "name", "missing")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+
+	t.Run("Empty field value", func(t *testing.T) {
+		_, err := urn.FromMessageFields(&userv1.UserPb{Alias: "sm", Name: "user"}, "alias", "name", "id")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+
+	t.Run("Nil message", func(t *testing.T) {
+		_, err := urn.FromMessageFields(nil, "a", "b", "c")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+}
+
+func TestURNConstraint(t *testing.T) {
+	mustParse := func(s string) urn.URN {
+		u, err := urn.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	testCases := []struct {
+		name       string
+		constraint urn.URNConstraint
+		pass       string
+		fail       string
+	}{
+		{
+			name:       "Namespaces",
+			constraint: urn.URNConstraint{Namespaces: []string{"sm", "contacts"}},
+			pass:       "urn:contacts:user:bob",
+			fail:       "urn:auth:user:bob",
+		},
+		{
+			name:       "Entity types",
+			constraint: urn.URNConstraint{EntityTypes: []string{"user"}},
+			pass:       "urn:sm:user:bob",
+			fail:       "urn:sm:group:bob",
+		},
+		{
+			name:       "ID pattern",
+			constraint: urn.URNConstraint{IDPattern: `^[a-z]+-[0-9]+$`},
+			pass:       "urn:sm:user:bob-123",
+			fail:       "urn:sm:user:Bob_123",
+		},
+		{
+			name:       "Minimum ID length",
+			constraint: urn.URNConstraint{MinIDLength: 4},
+			pass:       "urn:sm:user:abcd",
+			fail:       "urn:sm:user:abc",
+		},
+		{
+			name:       "Maximum ID length",
+			constraint: urn.URNConstraint{MaxIDLength: 4},
+			pass:       "urn:sm:user:abcd",
+			fail:       "urn:sm:user:abcde",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, tc.constraint.Validate(mustParse(tc.pass)))
+			assert.ErrorIs(t, tc.constraint.Validate(mustParse(tc.fail)), urn.ErrConstraintViolation)
+		})
+	}
+
+	t.Run("Empty constraint allows anything", func(t *testing.T) {
+		assert.NoError(t, urn.URNConstraint{}.Validate(mustParse("urn:any:thing:at-all")))
+	})
+
+	t.Run("Loads from JSON", func(t *testing.T) {
+		config := `{
+			"namespaces": ["sm"],
+			"entityTypes": ["user", "group"],
+			"idPattern": "^[a-z0-9-]+$",
+			"minIdLength": 3,
+			"maxIdLength": 36
+		}`
+
+		var c urn.URNConstraint
+		require.NoError(t, json.Unmarshal([]byte(config), &c))
+		assert.Equal(t, []string{"user", "group"}, c.EntityTypes)
+		assert.NoError(t, c.Validate(mustParse("urn:sm:group:team-42")))
+		assert.ErrorIs(t, c.Validate(mustParse("urn:sm:device:team-42")), urn.ErrConstraintViolation)
+
+		// A pattern changed after loading is not shadowed by the compiled one.
+		c.IDPattern = `^[0-9]+$`
+		assert.ErrorIs(t, c.Validate(mustParse("urn:sm:group:team-42")), urn.ErrConstraintViolation)
+	})
+
+	t.Run("Rejects an invalid pattern at load time", func(t *testing.T) {
+		var c urn.URNConstraint
+		err := json.Unmarshal([]byte(`{"idPattern": "[unclosed"}`), &c)
+		assert.ErrorContains(t, err, "invalid idPattern")
+	})
+}
+
+func TestNormalizeScheme(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Correct scheme", input: "urn:sm:user:x", expected: "urn:sm:user:x"},
+		{name: "Mis-cased scheme", input: "URN:sm:user:x", expected: "urn:sm:user:x"},
+		{name: "Mixed-case scheme", input: "Urn:auth:google:123", expected: "urn:auth:google:123"},
+		{name: "Missing scheme", input: "sm:user:x", expected: "urn:sm:user:x"},
+		{name: "Missing scheme, lookup namespace", input: "lookup:email:a@b.c", expected: "urn:lookup:email:a@b.c"},
+		{name: "Bare legacy ID", input: "user-123", expected: "user-123"},
+		{name: "Empty", input: "", expected: ""},
+		{name: "Wrong scheme", input: "http:sm:user:x", wantErr: true},
+		{name: "Unknown leading namespace", input: "custom:user:x", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, err := urn.NormalizeScheme(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+
+	t.Run("Registered namespace", func(t *testing.T) {
+		urn.RegisterNamespace("test-normalize")
+		normalized, err := urn.NormalizeScheme("test-normalize:item:x")
+		require.NoError(t, err)
+		assert.Equal(t, "urn:test-normalize:item:x", normalized)
+	})
+}
+
+// TestParse_StoredWhitespace checks that the decoding paths and New accept
+// stored URNs with whitespace in a part, while ParseLenient does not.
+func TestParse_StoredWhitespace(t *testing.T) {
+	const stored = "urn:sm:user:john doe"
+
+	want, err := urn.Parse(stored)
+	require.NoError(t, err)
+	assert.Equal(t, "john doe", want.EntityID())
+	assert.Equal(t, stored, want.String())
+
+	t.Run("Legacy bare ID", func(t *testing.T) {
+		u, promoted, err := urn.ParseURNOrUserID("john doe")
+		require.NoError(t, err)
+		assert.True(t, promoted)
+		assert.Equal(t, want, u)
+	})
+
+	t.Run("FromProto", func(t *testing.T) {
+		u, err := urn.FromProto(&netv1.UrnPb{Namespace: "sm", EntityType: "user", EntityId: "john doe"})
+		require.NoError(t, err)
+		assert.Equal(t, want, u)
+
+		u, err = urn.FromFields("sm", "user", "john doe")
+		require.NoError(t, err)
+		assert.Equal(t, want, u)
+	})
+
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		var u urn.URN
+		require.NoError(t, json.Unmarshal([]byte(`"`+stored+`"`), &u))
+		assert.Equal(t, want, u)
+	})
+
+	t.Run("New", func(t *testing.T) {
+		u, err := urn.New("sm", "user", "john doe")
+		require.NoError(t, err)
+		assert.Equal(t, want, u)
+	})
+
+	t.Run("ParseLenient rejects it", func(t *testing.T) {
+		_, err := urn.ParseLenient(stored)
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+}
+
+func TestParseLenient(t *testing.T) {
+	expected, err := urn.Parse("urn:sm:user:x")
+	require.NoError(t, err)
+
+	for _, input := range []string{"urn:sm:user:x", "URN:sm:user:x", "sm:user:x", "x"} {
+		u, err := urn.ParseLenient(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, u, input)
+	}
+
+	_, err = urn.ParseLenient("http:sm:user:x")
+	assert.Error(t, err)
+
+	t.Run("Surrounding whitespace is trimmed", func(t *testing.T) {
+		for _, input := range []string{" urn:sm:user:x", "urn:sm:user:x\n", "\t sm:user:x \r\n"} {
+			u, err := urn.ParseLenient(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, expected, u, input)
+		}
+		u, err := urn.ParseLenient("   ")
+		require.NoError(t, err)
+		assert.True(t, u.IsZero())
+	})
+
+	t.Run("Inner whitespace is rejected", func(t *testing.T) {
+		for _, input := range []string{"urn:sm:user:x y", "urn:sm: user:x", "urn:sm:user:x\ty"} {
+			_, err := urn.ParseLenient(input)
+			assert.ErrorIs(t, err, urn.ErrInvalidFormat, input)
+		}
+	})
+
+	_, err = urn.Parse(" urn:sm:user:x")
+	assert.Error(t, err, "Parse does not trim")
+
+	// Parse itself stays strict.
+	_, err = urn.Parse("URN:sm:user:x")
+	assert.Error(t, err)
+}
+
+func TestYAML_RoundTrip(t *testing.T) {
+	type config struct {
+		Owner urn.URN `yaml:"owner"`
+		Peer  urn.URN `yaml:"peer"`
+	}
+	u, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+
+	out, err := yaml.Marshal(config{Owner: u})
+	require.NoError(t, err)
+	assert.Equal(t, "owner: urn:sm:user:user-123\npeer: null\n", string(out))
+
+	var got config
+	require.NoError(t, yaml.Unmarshal(out, &got))
+	assert.Equal(t, u, got.Owner)
+	assert.True(t, got.Peer.IsZero())
+
+	require.NoError(t, yaml.Unmarshal([]byte(`peer: ""`), &got))
+	assert.True(t, got.Peer.IsZero())
+
+	err = yaml.Unmarshal([]byte(`owner: not-a-urn:x`), &got)
+	assert.Error(t, err)
+	err = yaml.Unmarshal([]byte(`owner: [a, b]`), &got)
+	assert.Error(t, err)
+}
+
+func TestIntern(t *testing.T) {
+	// Intern keeps no handles; hold them so a GC mid-test cannot drop the
+	// canonical copies.
+	handles := []unique.Handle[string]{unique.Make("sm"), unique.Make("fanout-recipient")}
+	defer runtime.KeepAlive(handles)
+
+	// Separate input strings, as when each envelope is decoded on its own
+	a, err := urn.Parse(strings.Clone("urn:sm:user:fanout-recipient"))
+	require.NoError(t, err)
+	b, err := urn.Parse(strings.Clone("urn:sm:user:fanout-recipient"))
+	require.NoError(t, err)
+	require.NotSame(t, unsafe.StringData(a.EntityID()), unsafe.StringData(b.EntityID()))
+
+	ia, ib := urn.Intern(a), urn.Intern(b)
+	assert.Equal(t, a, ia)
+	assert.Equal(t, b, ib)
+	assert.Same(t, unsafe.StringData(ia.EntityID()), unsafe.StringData(ib.EntityID()))
+	assert.Same(t, unsafe.StringData(ia.Namespace()), unsafe.StringData(ib.Namespace()))
+
+	assert.True(t, urn.Intern(urn.URN{}).IsZero())
+
+	u, err := urn.ParseInterned("urn:sm:user:fanout-recipient")
+	require.NoError(t, err)
+	assert.Same(t, unsafe.StringData(ia.EntityID()), unsafe.StringData(u.EntityID()))
+
+	_, err = urn.ParseInterned("urn:sm:user")
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+}
+
+// The parse benchmarks retain their results, as a fan-out does, and decode
+// from a fresh input string each time. Allocation per parse is the same; the
+// difference is what stays live, reported as the number of distinct entity ID
+// strings held by the last 1024 results.
+const benchURN = "urn:sm:user:fanout-recipient-0123456789"
+
+func reportDistinctIDs(b *testing.B, retained []urn.URN) {
+	b.Helper()
+	distinct := map[*byte]struct{}{}
+	for _, u := range retained {
+		if !u.IsZero() {
+			distinct[unsafe.StringData(u.EntityID())] = struct{}{}
+		}
+	}
+	b.ReportMetric(float64(len(distinct)), "distinct-ids")
+}
+
+func BenchmarkParse_Repeated(b *testing.B) {
+	retained := make([]urn.URN, 1024)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		u, err := urn.Parse(strings.Clone(benchURN))
+		if err != nil {
+			b.Fatal(err)
+		}
+		retained[i%len(retained)] = u
+		i++
+	}
+	reportDistinctIDs(b, retained)
+}
+
+func BenchmarkParseInterned_Repeated(b *testing.B) {
+	retained := make([]urn.URN, 1024)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		u, err := urn.ParseInterned(strings.Clone(benchURN))
+		if err != nil {
+			b.Fatal(err)
+		}
+		retained[i%len(retained)] = u
+		i++
+	}
+	reportDistinctIDs(b, retained)
+}
+
+func TestMsgpack_RoundTrip(t *testing.T) {
+	type record struct {
+		Owner urn.URN `msgpack:"owner"`
+		Peer  urn.URN `msgpack:"peer"`
+	}
+	u, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+
+	data, err := msgpack.Marshal(record{Owner: u})
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, msgpack.Unmarshal(data, &raw))
+	assert.Equal(t, map[string]any{"owner": "urn:sm:user:user-123", "peer": nil}, raw)
+
+	var got record
+	require.NoError(t, msgpack.Unmarshal(data, &got))
+	assert.Equal(t, u, got.Owner)
+	assert.True(t, got.Peer.IsZero())
+
+	bad, err := msgpack.Marshal(map[string]any{"owner": 42})
+	require.NoError(t, err)
+	assert.Error(t, msgpack.Unmarshal(bad, &got))
+}
+
+func TestCBOR_RoundTrip(t *testing.T) {
+	u, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+
+	data, err := cbor.Marshal(u)
+	require.NoError(t, err)
+	// Major type 3 (text string), length 20
+	assert.Equal(t, append([]byte{0x74}, "urn:sm:user:user-123"...), data)
+
+	var got urn.URN
+	require.NoError(t, cbor.Unmarshal(data, &got))
+	assert.Equal(t, u, got)
+
+	t.Run("Zero value", func(t *testing.T) {
+		data, err := cbor.Marshal(urn.URN{})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xf6}, data)
+
+		got := u
+		require.NoError(t, cbor.Unmarshal(data, &got))
+		assert.True(t, got.IsZero())
+	})
+
+	t.Run("Not a text string", func(t *testing.T) {
+		data, err := cbor.Marshal([]byte("urn:sm:user:x"))
+		require.NoError(t, err)
+		assert.Error(t, cbor.Unmarshal(data, &got))
+	})
+}
+
+func TestParse_MaxLength(t *testing.T) {
+	prefix := "urn:sm:user:"
+	atLimit := prefix + strings.Repeat("x", urn.MaxURNLength-len(prefix))
+	require.Len(t, atLimit, urn.MaxURNLength)
+
+	u, err := urn.Parse(atLimit)
+	require.NoError(t, err)
+	assert.Equal(t, atLimit, u.String())
+
+	_, err = urn.Parse(atLimit + "x")
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+
+	_, err = urn.Parse(strings.Repeat(":", 4<<20))
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+
+	// A legacy ID is measured by its upgraded urn:sm:user: form.
+	_, err = urn.Parse(strings.Repeat("x", urn.MaxURNLength-len(prefix)+1))
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+
+	t.Run("Configurable", func(t *testing.T) {
+		defer func(old int) { urn.MaxURNLength = old }(urn.MaxURNLength)
+		urn.MaxURNLength = 16
+		_, err := urn.Parse("urn:sm:user:12345")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+		_, err = urn.Parse("urn:sm:user:1234")
+		assert.NoError(t, err)
+	})
+}
+
+func TestParse_LookupNamespace(t *testing.T) {
+	for _, id := range []string{"a b", "key:with:colons", "user@example.com|tenant 7", "x/y:z"} {
+		t.Run(id, func(t *testing.T) {
+			u, err := urn.Parse("urn:lookup:ext:" + id)
+			require.NoError(t, err)
+			assert.Equal(t, urn.LookupNamespace, u.Namespace())
+			assert.Equal(t, id, u.EntityID())
+
+			again, err := urn.Parse(u.String())
+			require.NoError(t, err)
+			assert.Equal(t, u, again)
+
+			fromNew, err := urn.New(urn.LookupNamespace, "ext", id)
+			require.NoError(t, err)
+			assert.Equal(t, u, fromNew)
+		})
+	}
+
+	t.Run("Strict namespaces reject the same IDs", func(t *testing.T) {
+		for _, ns := range []string{urn.SecureMessaging, urn.AuthNamespace} {
+			_, err := urn.Parse("urn:" + ns + ":ext:key:with:colons")
+			assert.ErrorIs(t, err, urn.ErrInvalidFormat, ns)
+			_, err = urn.ParseLenient("urn:" + ns + ":ext:a b")
+			assert.ErrorIs(t, err, urn.ErrInvalidFormat, ns)
+		}
+	})
+
+	t.Run("ParseLenient accepts whitespace but not control characters", func(t *testing.T) {
+		u, err := urn.ParseLenient("urn:lookup:ext:a b")
+		require.NoError(t, err)
+		assert.Equal(t, "a b", u.EntityID())
+		_, err = urn.ParseLenient("urn:lookup:ext:a\nb")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+
+	t.Run("Only the entity ID is opaque", func(t *testing.T) {
+		_, err := urn.ParseLenient("urn:lookup:ext type:x")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+}
+
+// FuzzParse checks that Parse never panics and that any URN it returns
+// survives a String/Parse round trip.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"urn:sm:user:user-123",
+		"user-123",
+		":",
+		":::",
+		"::::",
+		"urn:",
+		"urn:sm:user:",
+		"urn:sm:user:x:",
+		"urn::user:x",
+		"URN:sm:user:x",
+		"urn:sm:thread:t1/message:m2",
+		"urn:sm:thread:/message:m2",
+		"urn:sm:thread:t1/",
+		"urn:sm:user:a b",
+		"urn:sm:user:\x00",
+		"urn:sm:user:\xff\xfe",
+		"urn:lookup:ext:a b:c",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := urn.Parse(s)
+		if err != nil {
+			assert.True(t, u.IsZero(), "a failed Parse must return the zero URN")
+			return
+		}
+		if u.IsZero() {
+			return
+		}
+		again, err := urn.Parse(u.String())
+		require.NoError(t, err, "re-parsing %q (from %q)", u.String(), s)
+		assert.Equal(t, u, again)
+	})
+}
+
+func TestURN_LogValue(t *testing.T) {
+	u, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+
+	assert.Equal(t, slog.KindString, u.LogValue().Kind())
+	assert.Equal(t, "urn:sm:user:user-123", u.LogValue().String())
+	assert.Equal(t, "", urn.URN{}.LogValue().String())
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("lookup", "recipient", u)
+	assert.Contains(t, buf.String(), "recipient=urn:sm:user:user-123")
+}
+
+func TestURN_In(t *testing.T) {
+	u, err := urn.Parse("urn:contacts:user:bob")
+	require.NoError(t, err)
+
+	assert.True(t, u.In(urn.SecureMessaging, "contacts"))
+	assert.False(t, u.In(urn.SecureMessaging, urn.AuthNamespace))
+	assert.False(t, u.In())
+	assert.False(t, urn.URN{}.In("", urn.SecureMessaging), "the zero URN is in no namespace")
+}
+
+func TestValidateRecipient(t *testing.T) {
+	namespaces := []string{urn.SecureMessaging, "contacts"}
+	types := []string{urn.EntityTypeUser}
+
+	testCases := []struct {
+		name    string
+		urn     string
+		wantErr string
+	}{
+		{name: "Allowed sm user", urn: "urn:sm:user:bob"},
+		{name: "Allowed contacts user", urn: "urn:contacts:user:bob"},
+		{name: "Disallowed namespace", urn: "urn:auth:user:bob", wantErr: `recipient urn:auth:user:bob: URN violates constraint: namespace "auth" is not allowed`},
+		{name: "Disallowed type", urn: "urn:sm:group:g1", wantErr: `recipient urn:sm:group:g1: URN violates constraint: entity type "group" is not allowed`},
+		{name: "Zero URN", urn: "", wantErr: "URN violates constraint: recipient is required"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := urn.Parse(tc.urn)
+			require.NoError(t, err)
+			err = urn.ValidateRecipient(u, namespaces, types)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, urn.ErrConstraintViolation)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("Empty lists allow any non-zero URN", func(t *testing.T) {
+		u, err := urn.Parse("urn:auth:group:g1")
+		require.NoError(t, err)
+		assert.NoError(t, urn.ValidateRecipient(u, nil, nil))
+		assert.ErrorIs(t, urn.ValidateRecipient(urn.URN{}, nil, nil), urn.ErrConstraintViolation)
+	})
+}
+
+func TestJoinSplitURNs(t *testing.T) {
+	mustParse := func(s string) urn.URN {
+		u, err := urn.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	testCases := []struct {
+		name   string
+		urns   []urn.URN
+		joined string
+	}{
+		{"Empty", []urn.URN{}, ""},
+		{"Single", []urn.URN{mustParse("urn:sm:user:a")}, "urn:sm:user:a"},
+		{"Several", []urn.URN{mustParse("urn:sm:user:a"), mustParse("urn:sm:group:g1")}, "urn:sm:user:a,urn:sm:group:g1"},
+		{"With a zero URN", []urn.URN{mustParse("urn:sm:user:a"), {}, mustParse("urn:sm:user:b")}, "urn:sm:user:a,%00,urn:sm:user:b"},
+		{"Only zero", []urn.URN{{}}, "%00"},
+		{"Commas and percents are escaped", []urn.URN{mustParse("urn:lookup:ext:a,b%2C"), mustParse("urn:sm:user:c")}, "urn:lookup:ext:a%2Cb%252C,urn:sm:user:c"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			joined := urn.JoinURNs(tc.urns)
+			assert.Equal(t, tc.joined, joined)
+
+			split, err := urn.SplitURNs(joined)
+			require.NoError(t, err)
+			assert.Equal(t, tc.urns, split)
+		})
+	}
+
+	t.Run("Nil list", func(t *testing.T) {
+		assert.Equal(t, "", urn.JoinURNs(nil))
+	})
+
+	t.Run("Empty element from older encoders", func(t *testing.T) {
+		split, err := urn.SplitURNs("urn:sm:user:a,,urn:sm:user:b")
+		require.NoError(t, err)
+		assert.Equal(t, []urn.URN{mustParse("urn:sm:user:a"), {}, mustParse("urn:sm:user:b")}, split)
+	})
+
+	t.Run("Percent in an ID is not the zero element", func(t *testing.T) {
+		u := mustParse("urn:lookup:ext:%00")
+		joined := urn.JoinURNs([]urn.URN{u})
+		assert.Equal(t, "urn:lookup:ext:%2500", joined)
+		split, err := urn.SplitURNs(joined)
+		require.NoError(t, err)
+		assert.Equal(t, []urn.URN{u}, split)
+	})
+
+	t.Run("Malformed element", func(t *testing.T) {
+		_, err := urn.SplitURNs("urn:sm:user:a,urn:sm:user")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+		assert.ErrorContains(t, err, "element 1")
+
+		_, err = urn.SplitURNs("urn:sm:user:a%ZZ")
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+}
+
+func TestParseURNOrUserID(t *testing.T) {
+	t.Run("Bare ID is promoted", func(t *testing.T) {
+		u, promoted, err := urn.ParseURNOrUserID("user-123")
+		require.NoError(t, err)
+		assert.True(t, promoted)
+		assert.Equal(t, "urn:sm:user:user-123", u.String())
+	})
+
+	t.Run("Full URN is not", func(t *testing.T) {
+		u, promoted, err := urn.ParseURNOrUserID("urn:sm:user:user-123")
+		require.NoError(t, err)
+		assert.False(t, promoted)
+		assert.Equal(t, "urn:sm:user:user-123", u.String())
+	})
+
+	t.Run("Same URN as Parse", func(t *testing.T) {
+		for _, s := range []string{"user-123", "urn:auth:google:42", ""} {
+			want, err := urn.Parse(s)
+			require.NoError(t, err)
+			got, _, err := urn.ParseURNOrUserID(s)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, s)
+		}
+	})
+
+	t.Run("Empty and invalid input", func(t *testing.T) {
+		u, promoted, err := urn.ParseURNOrUserID("")
+		require.NoError(t, err)
+		assert.False(t, promoted)
+		assert.True(t, u.IsZero())
+
+		for _, s := range []string{"urn:sm:user", "sm:user"} {
+			_, promoted, err = urn.ParseURNOrUserID(s)
+			assert.ErrorIs(t, err, urn.ErrInvalidFormat, s)
+			assert.False(t, promoted, s)
+		}
+	})
+}
@@ -2,15 +2,108 @@
 package notification
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
 
 	nv1 "github.com/tinywideclouds/gen-platform/go/types/notification/v1"
+	"github.com/tinywideclouds/go-platform/internal/convert"
+	"github.com/tinywideclouds/go-platform/internal/jsonext"
+	"github.com/tinywideclouds/go-platform/internal/openapi"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
+// DefaultMaxDataPayloadBytes is the FCM cap on the combined size of the
+// DataPayload keys and values.
+const DefaultMaxDataPayloadBytes = 4096
+
+var (
+	// ErrDataPayloadTooLarge is returned when the DataPayload exceeds its byte budget.
+	ErrDataPayloadTooLarge = errors.New("data payload exceeds size limit")
+	// ErrReservedDataKey is returned when a DataPayload key is reserved by the provider.
+	ErrReservedDataKey = errors.New("data payload uses a reserved key")
+	// ErrInvalidSubscription is wrapped by WebPushSubscription.Validate.
+	ErrInvalidSubscription = errors.New("invalid web push subscription")
+	// ErrInvalidRequest is wrapped by NotificationRequest.Validate.
+	ErrInvalidRequest = errors.New("invalid notification request")
+)
+
+// Decoded Web Push key sizes (RFC 8291): p256dh is an uncompressed P-256
+// point and auth a 16-byte secret.
+const (
+	WebPushP256dhLength = 65
+	WebPushAuthLength   = 16
+)
+
+// Metadata keys added by SplitPayload so the client can reassemble a payload.
+const (
+	PayloadChunkKey = "_chunk" // 0-based index of the chunk
+	PayloadTotalKey = "_total" // number of chunks
+)
+
+// reservedDataKeys and reservedDataKeyPrefixes are rejected by FCM.
+var (
+	reservedDataKeys        = []string{"from", "message_type"}
+	reservedDataKeyPrefixes = []string{"google.", "gcm."}
+)
+
+// Platform identifies the push service a request is delivered through.
+type Platform string
+
+const (
+	PlatformFCM  Platform = "fcm"
+	PlatformAPNS Platform = "apns"
+	PlatformWeb  Platform = "web"
+)
+
+// ErrPlatformConstraint is the sentinel wrapped by every PlatformError.
+var ErrPlatformConstraint = errors.New("platform constraint violated")
+
+// PlatformError reports the field of a request that a platform would reject.
+type PlatformError struct {
+	Platform Platform
+	Field    string
+	Reason   string
+}
+
+func (e *PlatformError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Platform, e.Field, e.Reason)
+}
+
+func (e *PlatformError) Unwrap() error {
+	return ErrPlatformConstraint
+}
+
+// platformConstraints holds the limits each push service enforces.
+// Web push allows 4096 bytes on the wire, less the aes128gcm overhead.
+var platformConstraints = map[Platform]struct {
+	maxPayloadBytes int
+	requireTitle    bool
+	noReservedKeys  bool
+}{
+	PlatformFCM:  {maxPayloadBytes: 4096, noReservedKeys: true},
+	PlatformAPNS: {maxPayloadBytes: 4096},
+	PlatformWeb:  {maxPayloadBytes: 3993, requireTitle: true},
+}
+
 // Re-export the Protobuf types.
 type NotificationRequestPb = nv1.NotificationRequestPb
+type NotificationContentPb = nv1.NotificationRequestPb_Content
 type WebPushSubscriptionPb = nv1.WebPushSubscriptionPb
 
 // --- Domain Structs ---
@@ -36,19 +129,68 @@ type NotificationRequest struct {
 	WebSubscriptions []WebPushSubscription `json:"webSubscriptions"`
 	Content          NotificationContent   `json:"content"`
 	DataPayload      map[string]string     `json:"dataPayload"`
+
+	// CampaignID and AnalyticsLabel attribute the notification to a product
+	// campaign. They are internal pipeline metadata: like the delivery
+	// targets, they travel in the JSON form but are stripped by
+	// NotificationRequestToProto, so they never reach the push provider.
+	CampaignID     string `json:"campaignId,omitempty"`
+	AnalyticsLabel string `json:"analyticsLabel,omitempty"`
+
+	// TargetPlatforms records the device platforms (TargetAndroid, TargetIOS,
+	// TargetWeb) the request was narrowed to by ForPlatforms. Empty means all.
+	TargetPlatforms []string `json:"targetPlatforms,omitempty"`
+
+	// ExpiresAt is the deadline, in Unix milliseconds, after which the
+	// notification is no longer worth sending; zero means it never expires.
+	// NotificationRequestPb has no field for it, so like the campaign
+	// metadata it travels in the JSON form only.
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// Device platforms accepted by ForPlatforms. FCM tokens reach Android and
+// iOS devices; web subscriptions reach browsers.
+const (
+	TargetAndroid = "android"
+	TargetIOS     = "ios"
+	TargetWeb     = "web"
+)
+
+// ForPlatforms returns a copy of r with only the delivery targets for the
+// given platforms: FCMTokens are kept for TargetAndroid or TargetIOS and
+// WebSubscriptions for TargetWeb. Platform names are case-insensitive and
+// unknown ones match nothing. The copy's target slices are its own; the
+// content and DataPayload are shared with r.
+func (r *NotificationRequest) ForPlatforms(platforms ...string) *NotificationRequest {
+	targets := make([]string, len(platforms))
+	for i, p := range platforms {
+		targets[i] = strings.ToLower(p)
+	}
+
+	filtered := *r
+	filtered.TargetPlatforms = targets
+	filtered.FCMTokens = nil
+	filtered.WebSubscriptions = nil
+	if slices.Contains(targets, TargetAndroid) || slices.Contains(targets, TargetIOS) {
+		filtered.FCMTokens = slices.Clone(r.FCMTokens)
+	}
+	if slices.Contains(targets, TargetWeb) {
+		filtered.WebSubscriptions = slices.Clone(r.WebSubscriptions)
+	}
+	return &filtered
 }
 
 // --- FACADE PATTERN IMPLEMENTATION ---
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 // It uses protojson to parse the wire format strictly, then maps it to the domain struct.
+// The key fields may be standard base64 or base64url, padded or not, so the
+// output of the browser's PushSubscription.toJSON() is accepted as-is.
 func (w *WebPushSubscription) UnmarshalJSON(data []byte) error {
 	var pb nv1.WebPushSubscriptionPb
 
 	// 1. Use protojson to parse the wire format (handling Base64, etc.)
-	// We use DiscardUnknown to allow forward compatibility.
-	opts := protojson.UnmarshalOptions{DiscardUnknown: true}
-	if err := opts.Unmarshal(data, &pb); err != nil {
+	if err := convert.UnmarshalOptions().Unmarshal(data, &pb); err != nil {
 		return err
 	}
 
@@ -62,7 +204,13 @@ func (w *WebPushSubscription) UnmarshalJSON(data []byte) error {
 
 // MarshalJSON implements the json.Marshaler interface.
 // It maps the domain struct to the Proto, then uses protojson to generate the wire format.
+// The key fields are always emitted as padded standard base64.
 func (w WebPushSubscription) MarshalJSON() ([]byte, error) {
+	return w.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options.
+func (w WebPushSubscription) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
 	// 1. Map Domain -> Proto
 	pb := &nv1.WebPushSubscriptionPb{
 		Endpoint: w.Endpoint,
@@ -71,22 +219,142 @@ func (w WebPushSubscription) MarshalJSON() ([]byte, error) {
 	}
 
 	// 2. Use protojson to generate JSON
-	opts := protojson.MarshalOptions{UseProtoNames: false, EmitUnpopulated: false}
 	return opts.Marshal(pb)
 }
 
-// ... (Existing NotificationRequestToProto / FromProto functions remain unchanged) ...
+// Validate checks that Endpoint is an absolute https URL and that the keys
+// have their decoded Web Push lengths. The keys are checked after decoding,
+// so either base64 alphabet is fine on the wire (see UnmarshalJSON). Errors
+// are *validation.FieldError values naming the field and wrapping
+// ErrInvalidSubscription.
+func (w WebPushSubscription) Validate() error {
+	endpoint, err := url.Parse(w.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return validation.NewFieldError("endpoint", "must be an absolute https URL", ErrInvalidSubscription)
+	}
+	if len(w.Keys.P256dh) != WebPushP256dhLength {
+		return validation.NewFieldError("keys.p256dh",
+			fmt.Sprintf("must be a %d-byte uncompressed P-256 point, got %d bytes", WebPushP256dhLength, len(w.Keys.P256dh)),
+			ErrInvalidSubscription)
+	}
+	if w.Keys.P256dh[0] != 0x04 {
+		return validation.NewFieldError("keys.p256dh",
+			fmt.Sprintf("must start with 0x04, got 0x%02x", w.Keys.P256dh[0]),
+			ErrInvalidSubscription)
+	}
+	if len(w.Keys.Auth) != WebPushAuthLength {
+		return validation.NewFieldError("keys.auth",
+			fmt.Sprintf("must be %d bytes, got %d", WebPushAuthLength, len(w.Keys.Auth)),
+			ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// NotificationContentToProto converts the content into its Protobuf representation.
+func NotificationContentToProto(native *NotificationContent) *NotificationContentPb {
+	if native == nil {
+		return nil
+	}
+	return &NotificationContentPb{
+		Title: native.Title,
+		Body:  native.Body,
+		Sound: native.Sound,
+	}
+}
+
+// NotificationContentFromProto converts the Protobuf content into the domain struct.
+func NotificationContentFromProto(proto *NotificationContentPb) *NotificationContent {
+	if proto == nil {
+		return nil
+	}
+	return &NotificationContent{
+		Title: proto.GetTitle(),
+		Body:  proto.GetBody(),
+		Sound: proto.GetSound(),
+	}
+}
+
+// MarshalJSON implements the json.Marshaler interface via the proto Content message.
+func (c NotificationContent) MarshalJSON() ([]byte, error) {
+	return c.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options.
+func (c NotificationContent) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	return opts.Marshal(NotificationContentToProto(&c))
+}
+
+// WithDefaults returns a copy of c with each empty field filled from
+// defaults, e.g. a per-app Sound. Fields already set in c are kept.
+func (c NotificationContent) WithDefaults(defaults NotificationContent) NotificationContent {
+	return NotificationContent{
+		Title: cmp.Or(c.Title, defaults.Title),
+		Body:  cmp.Or(c.Body, defaults.Body),
+		Sound: cmp.Or(c.Sound, defaults.Sound),
+	}
+}
+
+// Render returns a copy of c with Title and Body executed as text/template
+// templates against vars, e.g. "Hi {{.Name}}". A variable missing from vars
+// renders as the empty string; use RenderStrict to reject it instead. Sound
+// is copied unchanged.
+func (c NotificationContent) Render(vars map[string]string) (NotificationContent, error) {
+	return c.render(vars, "missingkey=zero")
+}
+
+// RenderStrict is Render that returns an error for any variable missing
+// from vars.
+func (c NotificationContent) RenderStrict(vars map[string]string) (NotificationContent, error) {
+	return c.render(vars, "missingkey=error")
+}
+
+func (c NotificationContent) render(vars map[string]string, missingKey string) (NotificationContent, error) {
+	title, err := renderField("title", c.Title, vars, missingKey)
+	if err != nil {
+		return NotificationContent{}, err
+	}
+	body, err := renderField("body", c.Body, vars, missingKey)
+	if err != nil {
+		return NotificationContent{}, err
+	}
+	return NotificationContent{Title: title, Body: body, Sound: c.Sound}, nil
+}
+
+func renderField(field, text string, vars map[string]string, missingKey string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(field).Option(missingKey).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", field, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", field, err)
+	}
+	return out.String(), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface via the proto Content message.
+func (c *NotificationContent) UnmarshalJSON(data []byte) error {
+	var pb NotificationContentPb
+	if err := convert.UnmarshalOptions().Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	*c = *NotificationContentFromProto(&pb)
+	return nil
+}
+
+// NotificationRequestToProto converts the request into its Protobuf
+// representation. The delivery targets, the campaign metadata and ExpiresAt
+// are not part of NotificationRequestPb and are dropped.
 func NotificationRequestToProto(nativeReq *NotificationRequest) *NotificationRequestPb {
 	if nativeReq == nil {
 		return nil
 	}
 	return &NotificationRequestPb{
 		RecipientId: nativeReq.RecipientID.String(),
-		Content: &nv1.NotificationRequestPb_Content{
-			Title: nativeReq.Content.Title,
-			Body:  nativeReq.Content.Body,
-			Sound: nativeReq.Content.Sound,
-		},
+		Content:     NotificationContentToProto(&nativeReq.Content),
 		DataPayload: nativeReq.DataPayload,
 	}
 }
@@ -97,15 +365,11 @@ func NotificationRequestFromProto(protoReq *NotificationRequestPb) (*Notificatio
 	}
 	recipientURN, err := urn.Parse(protoReq.GetRecipientId())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse recipient URN: %w", err)
+		return nil, validation.NewFieldError("recipientId", "failed to parse recipient URN", err)
 	}
 	var nativeContent NotificationContent
-	if protoReq.GetContent() != nil {
-		nativeContent = NotificationContent{
-			Title: protoReq.GetContent().GetTitle(),
-			Body:  protoReq.GetContent().GetBody(),
-			Sound: protoReq.GetContent().GetSound(),
-		}
+	if content := NotificationContentFromProto(protoReq.GetContent()); content != nil {
+		nativeContent = *content
 	}
 	return &NotificationRequest{
 		RecipientID:      recipientURN,
@@ -115,3 +379,247 @@ func NotificationRequestFromProto(protoReq *NotificationRequestPb) (*Notificatio
 		DataPayload:      protoReq.GetDataPayload(),
 	}, nil
 }
+
+// IsSilent reports whether the request is a silent (data-only) push: its
+// content has no title, body or sound.
+func (r *NotificationRequest) IsSilent() bool {
+	return r.Content == NotificationContent{}
+}
+
+// IsExpired reports whether the request's ExpiresAt deadline has passed at
+// now. A request without a deadline never expires.
+func (r *NotificationRequest) IsExpired(now time.Time) bool {
+	return r.ExpiresAt != 0 && now.UnixMilli() >= r.ExpiresAt
+}
+
+// Validate checks the request's structure before dispatch: RecipientID is
+// set, there is at least one delivery target, a non-silent request has a
+// title, and every web subscription is valid. All problems are reported at
+// once: the error wraps ErrInvalidRequest and an errors.Join of one
+// *validation.FieldError per problem.
+func (r *NotificationRequest) Validate() error {
+	var problems []error
+	if r.RecipientID.IsZero() {
+		problems = append(problems, validation.NewFieldError("recipientId", "is required", nil))
+	}
+	if len(r.FCMTokens) == 0 && len(r.WebSubscriptions) == 0 {
+		problems = append(problems, validation.NewFieldError("fcmTokens", "no delivery target: need an FCM token or a web subscription", nil))
+	}
+	if !r.IsSilent() && r.Content.Title == "" {
+		problems = append(problems, validation.NewFieldError("content.title", "is required unless the request is silent", nil))
+	}
+	for i, sub := range r.WebSubscriptions {
+		if err := sub.Validate(); err != nil {
+			problems = append(problems, validation.NewFieldError(fmt.Sprintf("webSubscriptions[%d]", i), "invalid subscription", err))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidRequest, errors.Join(problems...))
+}
+
+// ValidateDataPayload checks the DataPayload against the FCM defaults: no
+// reserved keys and at most DefaultMaxDataPayloadBytes of keys and values.
+func (r *NotificationRequest) ValidateDataPayload() error {
+	return r.ValidateDataPayloadSize(DefaultMaxDataPayloadBytes)
+}
+
+// ValidateDataPayloadSize is ValidateDataPayload with a caller-supplied byte budget.
+func (r *NotificationRequest) ValidateDataPayloadSize(maxBytes int) error {
+	size := 0
+	for _, key := range slices.Sorted(maps.Keys(r.DataPayload)) {
+		if isReservedDataKey(key) {
+			return fmt.Errorf("%w: %q", ErrReservedDataKey, key)
+		}
+		size += len(key) + len(r.DataPayload[key])
+	}
+	if size > maxBytes {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrDataPayloadTooLarge, size, maxBytes)
+	}
+	return nil
+}
+
+func isReservedDataKey(key string) bool {
+	if slices.Contains(reservedDataKeys, key) {
+		return true
+	}
+	for _, prefix := range reservedDataKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateForPlatform checks the request against the constraints of the push
+// service it will be sent through: payload size, required fields, reserved
+// data keys, and control characters in the content. Violations are reported
+// as a *PlatformError.
+func (r *NotificationRequest) ValidateForPlatform(p Platform) error {
+	limits, ok := platformConstraints[p]
+	if !ok {
+		return &PlatformError{Platform: p, Field: "platform", Reason: "unsupported platform"}
+	}
+
+	if limits.requireTitle && r.Content.Title == "" {
+		return &PlatformError{Platform: p, Field: "content.title", Reason: "is required"}
+	}
+	if r.Content == (NotificationContent{}) && len(r.DataPayload) == 0 {
+		return &PlatformError{Platform: p, Field: "content", Reason: "request has no content or data"}
+	}
+	if hasControlChars(r.Content.Title, false) {
+		return &PlatformError{Platform: p, Field: "content.title", Reason: "contains control characters"}
+	}
+	if hasControlChars(r.Content.Body, true) {
+		return &PlatformError{Platform: p, Field: "content.body", Reason: "contains control characters"}
+	}
+	if hasControlChars(r.Content.Sound, false) {
+		return &PlatformError{Platform: p, Field: "content.sound", Reason: "contains control characters"}
+	}
+
+	size := len(r.Content.Title) + len(r.Content.Body) + len(r.Content.Sound)
+	for _, key := range slices.Sorted(maps.Keys(r.DataPayload)) {
+		if limits.noReservedKeys && isReservedDataKey(key) {
+			return &PlatformError{Platform: p, Field: "dataPayload", Reason: fmt.Sprintf("reserved key %q", key)}
+		}
+		size += len(key) + len(r.DataPayload[key])
+	}
+	if size > limits.maxPayloadBytes {
+		return &PlatformError{
+			Platform: p,
+			Field:    "payload",
+			Reason:   fmt.Sprintf("%d bytes exceeds limit of %d", size, limits.maxPayloadBytes),
+		}
+	}
+	return nil
+}
+
+// hasControlChars reports whether s contains control characters, optionally
+// allowing the line breaks and tabs that are legitimate in a body.
+func hasControlChars(s string, allowWhitespace bool) bool {
+	return strings.ContainsFunc(s, func(r rune) bool {
+		if allowWhitespace && (r == '\n' || r == '\r' || r == '\t') {
+			return false
+		}
+		return unicode.IsControl(r)
+	})
+}
+
+// MarshalCanonical returns the JSON form with sorted keys, including the
+// DataPayload keys, and no insignificant whitespace, so equal requests always
+// give identical bytes.
+func (r *NotificationRequest) MarshalCanonical() ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return jsonext.Canonical(data)
+}
+
+// CacheKey returns a stable hex SHA-256 over what the request says and to whom:
+// the recipient, the content, and the DataPayload entries in sorted key order.
+// Delivery targets (FCMTokens, WebSubscriptions) are deliberately excluded, so
+// the key does not change with token ordering or a user's device churn.
+func (r *NotificationRequest) CacheKey() string {
+	h := sha256.New()
+	writeField(h, r.RecipientID.String())
+	writeField(h, r.Content.Title)
+	writeField(h, r.Content.Body)
+	writeField(h, r.Content.Sound)
+	for _, key := range slices.Sorted(maps.Keys(r.DataPayload)) {
+		writeField(h, key)
+		writeField(h, r.DataPayload[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField length-prefixes s so adjacent fields cannot run together.
+func writeField(h hash.Hash, s string) {
+	_ = binary.Write(h, binary.BigEndian, uint32(len(s)))
+	h.Write([]byte(s))
+}
+
+// SplitPayload partitions DataPayload across as many requests as needed for
+// each chunk's keys and values, including the PayloadChunkKey and
+// PayloadTotalKey metadata, to fit in maxBytes. Every chunk is a shallow copy
+// of r with the same recipient, content and targets.
+//
+// A payload that already fits is returned as a single request without
+// metadata. A single entry larger than maxBytes is placed in a chunk of its
+// own, which will still exceed the limit.
+//
+// A payload that already uses PayloadChunkKey or PayloadTotalKey is rejected
+// with ErrReservedDataKey, since the metadata would overwrite it.
+func (r *NotificationRequest) SplitPayload(maxBytes int) ([]*NotificationRequest, error) {
+	keys := slices.Sorted(maps.Keys(r.DataPayload))
+
+	size := 0
+	for _, key := range keys {
+		if key == PayloadChunkKey || key == PayloadTotalKey {
+			return nil, fmt.Errorf("%w: %q", ErrReservedDataKey, key)
+		}
+		size += len(key) + len(r.DataPayload[key])
+	}
+	if size <= maxBytes {
+		single := *r
+		return []*NotificationRequest{&single}, nil
+	}
+
+	// Reserve room for the metadata; there are never more chunks than entries.
+	digits := len(strconv.Itoa(len(keys)))
+	budget := maxBytes - (len(PayloadChunkKey) + len(PayloadTotalKey) + 2*digits)
+
+	var payloads []map[string]string
+	current, currentSize := map[string]string{}, 0
+	for _, key := range keys {
+		entrySize := len(key) + len(r.DataPayload[key])
+		if len(current) > 0 && currentSize+entrySize > budget {
+			payloads = append(payloads, current)
+			current, currentSize = map[string]string{}, 0
+		}
+		current[key] = r.DataPayload[key]
+		currentSize += entrySize
+	}
+	payloads = append(payloads, current)
+
+	chunks := make([]*NotificationRequest, len(payloads))
+	for i, payload := range payloads {
+		payload[PayloadChunkKey] = strconv.Itoa(i)
+		payload[PayloadTotalKey] = strconv.Itoa(len(payloads))
+		chunk := *r
+		chunk.DataPayload = payload
+		chunks[i] = &chunk
+	}
+	return chunks, nil
+}
+
+// --- Schema ---
+
+// OpenAPISchema returns the OpenAPI 3.1 schema object for the JSON form of
+// NotificationRequest. That form is written by encoding/json rather than
+// protojson, so the proto descriptor's fields are adjusted: the recipient,
+// delivery targets and DataPayload are null when unset, and the fields
+// NotificationRequestPb does not carry are added.
+func (r NotificationRequest) OpenAPISchema() (map[string]any, error) {
+	schema := openapi.Schema((&NotificationRequestPb{}).ProtoReflect().Descriptor())
+	props := openapi.Properties(schema)
+	props["recipientId"] = map[string]any{"type": []string{"string", "null"}}
+	props["dataPayload"].(map[string]any)["type"] = []string{"object", "null"}
+	props["fcmTokens"] = map[string]any{
+		"type":  []string{"array", "null"},
+		"items": map[string]any{"type": "string"},
+	}
+	props["webSubscriptions"] = map[string]any{
+		"type":  []string{"array", "null"},
+		"items": openapi.Schema((&WebPushSubscriptionPb{}).ProtoReflect().Descriptor()),
+	}
+	props["campaignId"] = map[string]any{"type": "string"}
+	props["analyticsLabel"] = map[string]any{"type": "string"}
+	props["targetPlatforms"] = map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string", "enum": []string{TargetAndroid, TargetIOS, TargetWeb}},
+	}
+	props["expiresAt"] = map[string]any{"type": "integer", "format": "int64"}
+	return schema, nil
+}
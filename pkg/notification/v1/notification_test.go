@@ -2,13 +2,20 @@
 package notification_test
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
 	"github.com/tinywideclouds/go-platform/pkg/notification/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
 )
 
 // newTestRequest creates a populated NotificationRequest for testing.
@@ -97,6 +104,41 @@ func TestWebPushSubscription_JSON_Facade(t *testing.T) {
 		assert.Equal(t, original, result)
 	})
 
+	t.Run("UnmarshalJSON accepts base64url browser keys", func(t *testing.T) {
+		// Arrange: Keys as taken from PushSubscription.toJSON() (unpadded base64url)
+		browserJSON := `{
+			"endpoint": "https://fcm.googleapis.com/fcm/send/eR5",
+			"expirationTime": null,
+			"p256dh": "BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM",
+			"auth": "tBHItJI5svbpez7KI4CCXg"
+		}`
+		var loaded notification.WebPushSubscription
+
+		// Act
+		err := json.Unmarshal([]byte(browserJSON), &loaded)
+		require.NoError(t, err)
+
+		// Assert: Keys are normalized to raw bytes
+		assert.Len(t, loaded.Keys.P256dh, 65)
+		assert.Equal(t, byte(0x04), loaded.Keys.P256dh[0])
+		assert.Len(t, loaded.Keys.Auth, 16)
+
+		// Marshaling re-emits padded standard base64
+		data, err := json.Marshal(loaded)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"auth":"tBHItJI5svbpez7KI4CCXg=="`)
+	})
+
+	t.Run("UnmarshalJSON accepts every base64 variant", func(t *testing.T) {
+		// 0xfb 0xff encodes to "+/8" (std) or "-_8" (url).
+		for _, encoded := range []string{"+/8=", "+/8", "-_8=", "-_8"} {
+			var loaded notification.WebPushSubscription
+			err := json.Unmarshal([]byte(`{"auth":"`+encoded+`"}`), &loaded)
+			require.NoError(t, err, encoded)
+			assert.Equal(t, []byte{0xfb, 0xff}, loaded.Keys.Auth, encoded)
+		}
+	})
+
 	t.Run("Handles Invalid JSON via protojson error", func(t *testing.T) {
 		// Arrange: Invalid JSON (wrong type for keys)
 		invalidJSON := `{"endpoint": 123}`
@@ -110,6 +152,50 @@ func TestWebPushSubscription_JSON_Facade(t *testing.T) {
 	})
 }
 
+func TestNotificationContent_JSON_RoundTrip(t *testing.T) {
+	original := notification.NotificationContent{
+		Title: "New Message",
+		Body:  "You have a new secure message.",
+		Sound: "default",
+	}
+	expectedJSON := `{"title":"New Message","body":"You have a new secure message.","sound":"default"}`
+
+	t.Run("MarshalJSON", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(original)
+		require.NoError(t, err)
+		assert.JSONEq(t, expectedJSON, string(jsonBytes))
+	})
+
+	t.Run("MarshalJSON omits empty fields", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(notification.NotificationContent{Title: "Hi"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"title":"Hi"}`, string(jsonBytes))
+	})
+
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		var loaded notification.NotificationContent
+		require.NoError(t, json.Unmarshal([]byte(expectedJSON), &loaded))
+		assert.Equal(t, original, loaded)
+	})
+
+	t.Run("UnmarshalJSON with unknown fields", func(t *testing.T) {
+		jsonWithExtra := `{"title":"New Message","body":"You have a new secure message.","sound":"default","badge":3}`
+		var loaded notification.NotificationContent
+		require.NoError(t, json.Unmarshal([]byte(jsonWithExtra), &loaded))
+		assert.Equal(t, original, loaded)
+	})
+
+	t.Run("Nested in NotificationRequest", func(t *testing.T) {
+		req := newTestRequest(t)
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		var loaded notification.NotificationRequest
+		require.NoError(t, json.Unmarshal(data, &loaded))
+		assert.Equal(t, req, &loaded)
+	})
+}
+
 // ... (Existing NotificationRequest tests) ...
 
 func TestNotificationRequestConversions(t *testing.T) {
@@ -139,4 +225,637 @@ func TestNotificationRequestConversions(t *testing.T) {
 		require.Nil(t, convertedNative.FCMTokens)
 		require.Nil(t, convertedNative.WebSubscriptions)
 	})
+
+	t.Run("FromProto Reports The Bad Field", func(t *testing.T) {
+		protoReq := notification.NotificationRequestToProto(nativeReq)
+		protoReq.RecipientId = "urn:a:b"
+
+		_, err := notification.NotificationRequestFromProto(protoReq)
+		var fe *validation.FieldError
+		require.ErrorAs(t, err, &fe)
+		assert.Equal(t, "recipientId", fe.Field())
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+}
+
+func TestNotificationRequest_ValidateDataPayload(t *testing.T) {
+	t.Run("Valid payload", func(t *testing.T) {
+		req := newTestRequest(t)
+		assert.NoError(t, req.ValidateDataPayload())
+	})
+
+	t.Run("Oversized payload", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.DataPayload["blob"] = strings.Repeat("x", notification.DefaultMaxDataPayloadBytes)
+
+		err := req.ValidateDataPayload()
+		assert.ErrorIs(t, err, notification.ErrDataPayloadTooLarge)
+	})
+
+	t.Run("Custom budget", func(t *testing.T) {
+		req := newTestRequest(t)
+		// "message_id" + "msg-789" is 17 bytes.
+		assert.NoError(t, req.ValidateDataPayloadSize(17))
+		assert.ErrorIs(t, req.ValidateDataPayloadSize(16), notification.ErrDataPayloadTooLarge)
+	})
+
+	t.Run("Reserved keys", func(t *testing.T) {
+		for _, key := range []string{"from", "message_type", "google.c.a.e", "gcm.n.e"} {
+			req := newTestRequest(t)
+			req.DataPayload[key] = "1"
+
+			err := req.ValidateDataPayload()
+			assert.ErrorIs(t, err, notification.ErrReservedDataKey, key)
+		}
+	})
+}
+
+func TestNotificationRequest_ValidateForPlatform(t *testing.T) {
+	platforms := []notification.Platform{
+		notification.PlatformFCM,
+		notification.PlatformAPNS,
+		notification.PlatformWeb,
+	}
+
+	t.Run("Conforming request", func(t *testing.T) {
+		for _, p := range platforms {
+			assert.NoError(t, newTestRequest(t).ValidateForPlatform(p), p)
+		}
+	})
+
+	t.Run("Oversized payload", func(t *testing.T) {
+		for _, p := range platforms {
+			req := newTestRequest(t)
+			req.Content.Body = strings.Repeat("x", 4096)
+
+			err := req.ValidateForPlatform(p)
+
+			var platformErr *notification.PlatformError
+			require.ErrorAs(t, err, &platformErr, p)
+			assert.Equal(t, p, platformErr.Platform)
+			assert.Equal(t, "payload", platformErr.Field)
+			assert.ErrorIs(t, err, notification.ErrPlatformConstraint)
+		}
+	})
+
+	t.Run("Control characters in title", func(t *testing.T) {
+		for _, p := range platforms {
+			req := newTestRequest(t)
+			req.Content.Title = "New\x00Message"
+
+			var platformErr *notification.PlatformError
+			require.ErrorAs(t, req.ValidateForPlatform(p), &platformErr, p)
+			assert.Equal(t, "content.title", platformErr.Field)
+		}
+	})
+
+	t.Run("Line breaks allowed in body", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.Content.Body = "Line one\nLine two"
+		for _, p := range platforms {
+			assert.NoError(t, req.ValidateForPlatform(p), p)
+		}
+	})
+
+	t.Run("FCM rejects reserved data keys", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.DataPayload["google.c.a.e"] = "1"
+
+		var platformErr *notification.PlatformError
+		require.ErrorAs(t, req.ValidateForPlatform(notification.PlatformFCM), &platformErr)
+		assert.Equal(t, "dataPayload", platformErr.Field)
+
+		// Only FCM reserves these keys.
+		assert.NoError(t, req.ValidateForPlatform(notification.PlatformAPNS))
+	})
+
+	t.Run("APNS rejects an empty request", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.Content = notification.NotificationContent{}
+		req.DataPayload = nil
+
+		var platformErr *notification.PlatformError
+		require.ErrorAs(t, req.ValidateForPlatform(notification.PlatformAPNS), &platformErr)
+		assert.Equal(t, "content", platformErr.Field)
+	})
+
+	t.Run("Web requires a title", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.Content.Title = ""
+
+		var platformErr *notification.PlatformError
+		require.ErrorAs(t, req.ValidateForPlatform(notification.PlatformWeb), &platformErr)
+		assert.Equal(t, "content.title", platformErr.Field)
+
+		assert.NoError(t, req.ValidateForPlatform(notification.PlatformFCM))
+	})
+
+	t.Run("Unsupported platform", func(t *testing.T) {
+		err := newTestRequest(t).ValidateForPlatform("carrier-pigeon")
+		assert.ErrorIs(t, err, notification.ErrPlatformConstraint)
+	})
+}
+
+func TestNotificationRequest_CacheKey(t *testing.T) {
+	base := newTestRequest(t)
+	key := base.CacheKey()
+	assert.Len(t, key, 64)
+
+	t.Run("Stable across token reordering", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.FCMTokens = []string{"fcm-token-2", "fcm-token-1"}
+		req.WebSubscriptions = nil
+		assert.Equal(t, key, req.CacheKey())
+	})
+
+	t.Run("Stable across payload insertion order", func(t *testing.T) {
+		a := newTestRequest(t)
+		b := newTestRequest(t)
+		a.DataPayload = map[string]string{"a": "1", "b": "2", "c": "3"}
+		b.DataPayload = map[string]string{"c": "3", "b": "2", "a": "1"}
+		assert.Equal(t, a.CacheKey(), b.CacheKey())
+	})
+
+	t.Run("Changes with content", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.Content.Body = "Something else"
+		assert.NotEqual(t, key, req.CacheKey())
+	})
+
+	t.Run("Changes with payload", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.DataPayload["message_id"] = "msg-790"
+		assert.NotEqual(t, key, req.CacheKey())
+	})
+
+	t.Run("Field boundaries are unambiguous", func(t *testing.T) {
+		a := newTestRequest(t)
+		b := newTestRequest(t)
+		a.Content.Title, a.Content.Body = "ab", "c"
+		b.Content.Title, b.Content.Body = "a", "bc"
+		assert.NotEqual(t, a.CacheKey(), b.CacheKey())
+	})
+}
+
+func TestNotificationRequest_SplitPayload(t *testing.T) {
+	// reassemble merges chunk payloads back into one, checking the metadata.
+	reassemble := func(t *testing.T, chunks []*notification.NotificationRequest) map[string]string {
+		t.Helper()
+		merged := map[string]string{}
+		for i, chunk := range chunks {
+			assert.Equal(t, strconv.Itoa(i), chunk.DataPayload[notification.PayloadChunkKey])
+			assert.Equal(t, strconv.Itoa(len(chunks)), chunk.DataPayload[notification.PayloadTotalKey])
+			for k, v := range chunk.DataPayload {
+				if k != notification.PayloadChunkKey && k != notification.PayloadTotalKey {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	t.Run("Payload within limit is not split", func(t *testing.T) {
+		req := newTestRequest(t)
+
+		chunks, err := req.SplitPayload(notification.DefaultMaxDataPayloadBytes)
+		require.NoError(t, err)
+
+		require.Len(t, chunks, 1)
+		assert.Equal(t, req, chunks[0])
+		assert.NotSame(t, req, chunks[0])
+	})
+
+	t.Run("Chunks fit and reassemble", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.DataPayload = map[string]string{}
+		for i := range 20 {
+			req.DataPayload[fmt.Sprintf("key-%02d", i)] = strings.Repeat("v", 100)
+		}
+		const maxBytes = 512
+
+		chunks, err := req.SplitPayload(maxBytes)
+		require.NoError(t, err)
+
+		require.Greater(t, len(chunks), 1)
+		for _, chunk := range chunks {
+			assert.NoError(t, chunk.ValidateDataPayloadSize(maxBytes))
+			assert.Equal(t, req.RecipientID, chunk.RecipientID)
+			assert.Equal(t, req.Content, chunk.Content)
+		}
+		assert.Equal(t, req.DataPayload, reassemble(t, chunks))
+	})
+
+	t.Run("Oversized entry gets its own chunk", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.DataPayload = map[string]string{
+			"a":    "small",
+			"big":  strings.Repeat("x", 200),
+			"zzzz": "small",
+		}
+
+		chunks, err := req.SplitPayload(64)
+		require.NoError(t, err)
+
+		require.Len(t, chunks, 3)
+		assert.Contains(t, chunks[1].DataPayload, "big")
+		assert.Equal(t, req.DataPayload, reassemble(t, chunks))
+	})
+
+	t.Run("Reserved metadata keys are rejected", func(t *testing.T) {
+		for _, key := range []string{notification.PayloadChunkKey, notification.PayloadTotalKey} {
+			req := newTestRequest(t)
+			req.DataPayload = map[string]string{"a": "small", key: "7"}
+
+			_, err := req.SplitPayload(notification.DefaultMaxDataPayloadBytes)
+			assert.ErrorIs(t, err, notification.ErrReservedDataKey, key)
+			_, err = req.SplitPayload(1)
+			assert.ErrorIs(t, err, notification.ErrReservedDataKey, key)
+		}
+	})
+}
+
+func TestNotificationRequest_MarshalCanonical(t *testing.T) {
+	req := newTestRequest(t)
+	req.DataPayload = map[string]string{}
+	for i := range 50 {
+		req.DataPayload["key-"+strconv.Itoa(i)] = strconv.Itoa(i)
+	}
+
+	first, err := req.MarshalCanonical()
+	require.NoError(t, err)
+	for range 100 {
+		again, err := req.MarshalCanonical()
+		require.NoError(t, err)
+		require.Equal(t, string(first), string(again))
+	}
+
+	// A copy built in a different insertion order gives the same bytes
+	other := *req
+	other.DataPayload = map[string]string{}
+	for i := 49; i >= 0; i-- {
+		other.DataPayload["key-"+strconv.Itoa(i)] = strconv.Itoa(i)
+	}
+	otherBytes, err := other.MarshalCanonical()
+	require.NoError(t, err)
+	assert.Equal(t, string(first), string(otherBytes))
+
+	assert.NotContains(t, string(first), "\n")
+	assert.True(t, strings.HasPrefix(string(first), `{"content":{`), "keys should be sorted")
+}
+
+func TestWebPushSubscription_Validate(t *testing.T) {
+	p256dh := append([]byte{0x04}, bytes.Repeat([]byte{0xab}, 64)...)
+	auth := bytes.Repeat([]byte{0x01}, 16)
+	valid := func() notification.WebPushSubscription {
+		var w notification.WebPushSubscription
+		w.Endpoint = "https://fcm.googleapis.com/fcm/send/abc"
+		w.Keys.P256dh = p256dh
+		w.Keys.Auth = auth
+		return w
+	}
+
+	t.Run("Correct lengths", func(t *testing.T) {
+		assert.NoError(t, valid().Validate())
+	})
+
+	t.Run("Either base64 alphabet on the wire", func(t *testing.T) {
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawURLEncoding} {
+			input := fmt.Sprintf(`{"endpoint":"https://push.example.com/x","p256dh":%q,"auth":%q}`,
+				enc.EncodeToString(p256dh), enc.EncodeToString(auth))
+			var w notification.WebPushSubscription
+			require.NoError(t, json.Unmarshal([]byte(input), &w))
+			assert.NoError(t, w.Validate())
+		}
+	})
+
+	testCases := []struct {
+		name   string
+		mutate func(*notification.WebPushSubscription)
+		field  string
+	}{
+		{"Short p256dh", func(w *notification.WebPushSubscription) { w.Keys.P256dh = p256dh[:33] }, "keys.p256dh"},
+		{"Long p256dh", func(w *notification.WebPushSubscription) { w.Keys.P256dh = append(bytes.Clone(p256dh), 0) }, "keys.p256dh"},
+		{"Compressed p256dh", func(w *notification.WebPushSubscription) {
+			w.Keys.P256dh = append([]byte{0x02}, p256dh[1:]...)
+		}, "keys.p256dh"},
+		{"Missing p256dh", func(w *notification.WebPushSubscription) { w.Keys.P256dh = nil }, "keys.p256dh"},
+		{"Short auth", func(w *notification.WebPushSubscription) { w.Keys.Auth = auth[:8] }, "keys.auth"},
+		{"Long auth", func(w *notification.WebPushSubscription) { w.Keys.Auth = append(bytes.Clone(auth), 0) }, "keys.auth"},
+		{"Plain http endpoint", func(w *notification.WebPushSubscription) { w.Endpoint = "http://push.example.com/x" }, "endpoint"},
+		{"Relative endpoint", func(w *notification.WebPushSubscription) { w.Endpoint = "/fcm/send/abc" }, "endpoint"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := valid()
+			tc.mutate(&w)
+			err := w.Validate()
+			require.ErrorIs(t, err, notification.ErrInvalidSubscription)
+			fieldErr, ok := validation.AsFieldError(err)
+			require.True(t, ok)
+			assert.Equal(t, tc.field, fieldErr.Field())
+		})
+	}
+
+	t.Run("p256dh reasons", func(t *testing.T) {
+		w := valid()
+		w.Keys.P256dh = p256dh[:33]
+		assert.ErrorContains(t, w.Validate(), "must be a 65-byte uncompressed P-256 point, got 33 bytes")
+
+		w.Keys.P256dh = append([]byte{0x02}, p256dh[1:]...)
+		assert.ErrorContains(t, w.Validate(), "must start with 0x04, got 0x02")
+	})
+}
+
+func TestNotificationRequest_CampaignMetadata(t *testing.T) {
+	req := newTestRequest(t)
+	req.CampaignID = "spring-2026"
+	req.AnalyticsLabel = "reengage_d7"
+
+	t.Run("JSON round trip", func(t *testing.T) {
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"campaignId":"spring-2026"`)
+		assert.Contains(t, string(data), `"analyticsLabel":"reengage_d7"`)
+
+		var got notification.NotificationRequest
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, req.CampaignID, got.CampaignID)
+		assert.Equal(t, req.AnalyticsLabel, got.AnalyticsLabel)
+	})
+
+	t.Run("Stripped by ToProto", func(t *testing.T) {
+		got, err := notification.NotificationRequestFromProto(notification.NotificationRequestToProto(req))
+		require.NoError(t, err)
+		assert.Empty(t, got.CampaignID)
+		assert.Empty(t, got.AnalyticsLabel)
+		assert.Equal(t, req.Content, got.Content)
+	})
+
+	t.Run("Kept by SplitPayload", func(t *testing.T) {
+		chunks, err := req.SplitPayload(1)
+		require.NoError(t, err)
+		for _, chunk := range chunks {
+			assert.Equal(t, req.CampaignID, chunk.CampaignID)
+		}
+	})
+}
+
+func TestNotificationContent_Render(t *testing.T) {
+	content := notification.NotificationContent{
+		Title: "Hi {{.Name}}",
+		Body:  "{{.Sender}} sent you {{.Count}} messages",
+		Sound: "{{.NotATemplate}}",
+	}
+	vars := map[string]string{"Name": "Alice", "Sender": "Bob", "Count": "3"}
+
+	t.Run("Success", func(t *testing.T) {
+		for _, render := range []func(map[string]string) (notification.NotificationContent, error){content.Render, content.RenderStrict} {
+			got, err := render(vars)
+			require.NoError(t, err)
+			assert.Equal(t, notification.NotificationContent{
+				Title: "Hi Alice",
+				Body:  "Bob sent you 3 messages",
+				Sound: "{{.NotATemplate}}",
+			}, got)
+		}
+		assert.Equal(t, "Hi {{.Name}}", content.Title, "the receiver is unchanged")
+	})
+
+	t.Run("Missing variable", func(t *testing.T) {
+		partial := map[string]string{"Name": "Alice"}
+
+		got, err := content.Render(partial)
+		require.NoError(t, err)
+		assert.Equal(t, " sent you  messages", got.Body)
+
+		_, err = content.RenderStrict(partial)
+		assert.ErrorContains(t, err, "body")
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		_, err := notification.NotificationContent{Title: "Hi {{.Name"}.Render(vars)
+		assert.ErrorContains(t, err, "title")
+	})
+}
+
+func TestNotificationRequest_ForPlatforms(t *testing.T) {
+	req := newTestRequest(t)
+	require.NotEmpty(t, req.FCMTokens)
+	require.NotEmpty(t, req.WebSubscriptions)
+
+	t.Run("Web only", func(t *testing.T) {
+		web := req.ForPlatforms(notification.TargetWeb)
+		assert.Empty(t, web.FCMTokens)
+		assert.Equal(t, req.WebSubscriptions, web.WebSubscriptions)
+		assert.Equal(t, []string{"web"}, web.TargetPlatforms)
+		assert.Equal(t, req.Content, web.Content)
+	})
+
+	t.Run("Android only", func(t *testing.T) {
+		android := req.ForPlatforms("Android")
+		assert.Equal(t, req.FCMTokens, android.FCMTokens)
+		assert.Empty(t, android.WebSubscriptions)
+		assert.Equal(t, []string{"android"}, android.TargetPlatforms)
+
+		android.FCMTokens[0] = "changed"
+		assert.NotEqual(t, "changed", req.FCMTokens[0], "the copy does not alias r's targets")
+	})
+
+	t.Run("Unknown platform", func(t *testing.T) {
+		none := req.ForPlatforms("symbian")
+		assert.Empty(t, none.FCMTokens)
+		assert.Empty(t, none.WebSubscriptions)
+	})
+
+	t.Run("Receiver unchanged", func(t *testing.T) {
+		assert.Empty(t, req.TargetPlatforms)
+		assert.Len(t, req.WebSubscriptions, 1)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(req.ForPlatforms(notification.TargetIOS, notification.TargetWeb))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"targetPlatforms":["ios","web"]`)
+	})
+}
+
+func TestNotificationRequest_Validate(t *testing.T) {
+	validRequest := func(t *testing.T) *notification.NotificationRequest {
+		req := newTestRequest(t)
+		req.WebSubscriptions[0].Keys.P256dh = append([]byte{0x04}, bytes.Repeat([]byte{0xab}, 64)...)
+		req.WebSubscriptions[0].Keys.Auth = bytes.Repeat([]byte{0x01}, 16)
+		return req
+	}
+	fields := func(err error) []string {
+		var out []string
+		type multi interface{ Unwrap() []error }
+		joined := err.(multi).Unwrap()[1]
+		for _, e := range joined.(multi).Unwrap() {
+			fe, ok := validation.AsFieldError(e)
+			require.True(t, ok)
+			out = append(out, fe.Field())
+		}
+		return out
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, validRequest(t).Validate())
+	})
+
+	testCases := []struct {
+		name   string
+		mutate func(*notification.NotificationRequest)
+		field  string
+	}{
+		{"Missing recipient", func(r *notification.NotificationRequest) { r.RecipientID = urn.URN{} }, "recipientId"},
+		{"No targets", func(r *notification.NotificationRequest) {
+			r.FCMTokens = nil
+			r.WebSubscriptions = nil
+		}, "fcmTokens"},
+		{"Missing title", func(r *notification.NotificationRequest) { r.Content.Title = "" }, "content.title"},
+		{"Invalid web subscription", func(r *notification.NotificationRequest) { r.WebSubscriptions[0].Keys.Auth = nil }, "webSubscriptions[0]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := validRequest(t)
+			tc.mutate(req)
+			err := req.Validate()
+			require.ErrorIs(t, err, notification.ErrInvalidRequest)
+			assert.Equal(t, []string{tc.field}, fields(err))
+		})
+	}
+
+	t.Run("Invalid subscription keeps its cause", func(t *testing.T) {
+		req := validRequest(t)
+		req.WebSubscriptions[0].Endpoint = "http://insecure.example.com"
+		assert.ErrorIs(t, req.Validate(), notification.ErrInvalidSubscription)
+	})
+
+	t.Run("Silent request needs no title", func(t *testing.T) {
+		req := validRequest(t)
+		req.Content = notification.NotificationContent{}
+		assert.True(t, req.IsSilent())
+		assert.NoError(t, req.Validate())
+	})
+
+	t.Run("All problems at once", func(t *testing.T) {
+		req := &notification.NotificationRequest{Content: notification.NotificationContent{Body: "no title"}}
+		err := req.Validate()
+		require.ErrorIs(t, err, notification.ErrInvalidRequest)
+		assert.Equal(t, []string{"recipientId", "fcmTokens", "content.title"}, fields(err))
+	})
+}
+
+func TestNotificationRequest_IsExpired(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+
+	t.Run("Expired", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.ExpiresAt = now.Add(-time.Minute).UnixMilli()
+		assert.True(t, req.IsExpired(now))
+
+		req.ExpiresAt = now.UnixMilli()
+		assert.True(t, req.IsExpired(now), "the deadline itself counts as expired")
+	})
+
+	t.Run("Live", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.ExpiresAt = now.Add(time.Minute).UnixMilli()
+		assert.False(t, req.IsExpired(now))
+	})
+
+	t.Run("Unset never expires", func(t *testing.T) {
+		req := newTestRequest(t)
+		assert.False(t, req.IsExpired(now))
+		assert.False(t, req.IsExpired(time.UnixMilli(1<<62)))
+	})
+
+	t.Run("JSON round trip", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.ExpiresAt = now.UnixMilli()
+
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"expiresAt":1700000000000`)
+
+		var got notification.NotificationRequest
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, req.ExpiresAt, got.ExpiresAt)
+
+		unset, err := json.Marshal(newTestRequest(t))
+		require.NoError(t, err)
+		assert.NotContains(t, string(unset), "expiresAt")
+	})
+
+	t.Run("Stripped by ToProto", func(t *testing.T) {
+		req := newTestRequest(t)
+		req.ExpiresAt = now.UnixMilli()
+		got, err := notification.NotificationRequestFromProto(notification.NotificationRequestToProto(req))
+		require.NoError(t, err)
+		assert.Zero(t, got.ExpiresAt)
+	})
+}
+
+func TestNotificationContent_WithDefaults(t *testing.T) {
+	defaults := notification.NotificationContent{Title: "App", Body: "You have a new message", Sound: "chime.caf"}
+
+	testCases := []struct {
+		name    string
+		content notification.NotificationContent
+		want    notification.NotificationContent
+	}{
+		{
+			name:    "Empty content takes every default",
+			content: notification.NotificationContent{},
+			want:    defaults,
+		},
+		{
+			name:    "Only the sound is filled",
+			content: notification.NotificationContent{Title: "Alice", Body: "Hi!"},
+			want:    notification.NotificationContent{Title: "Alice", Body: "Hi!", Sound: "chime.caf"},
+		},
+		{
+			name:    "Title and body are filled",
+			content: notification.NotificationContent{Sound: "ping.caf"},
+			want:    notification.NotificationContent{Title: "App", Body: "You have a new message", Sound: "ping.caf"},
+		},
+		{
+			name:    "Set fields win",
+			content: notification.NotificationContent{Title: "Alice", Body: "Hi!", Sound: "ping.caf"},
+			want:    notification.NotificationContent{Title: "Alice", Body: "Hi!", Sound: "ping.caf"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.content.WithDefaults(defaults))
+		})
+	}
+
+	t.Run("Empty defaults change nothing", func(t *testing.T) {
+		content := notification.NotificationContent{Title: "Alice"}
+		assert.Equal(t, content, content.WithDefaults(notification.NotificationContent{}))
+	})
+}
+
+func TestNotificationRequest_OpenAPISchema(t *testing.T) {
+	schema, err := notification.NotificationRequest{}.OpenAPISchema()
+	require.NoError(t, err)
+
+	// Every member of the JSON form is described
+	req := newTestRequest(t)
+	req.CampaignID, req.AnalyticsLabel, req.ExpiresAt = "c", "l", 1
+	req.TargetPlatforms = []string{notification.TargetWeb}
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	var members map[string]any
+	require.NoError(t, json.Unmarshal(data, &members))
+
+	props := schema["properties"].(map[string]any)
+	for member := range members {
+		assert.Contains(t, props, member)
+	}
+	content := props["content"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string"}, content["title"])
+	sub := props["webSubscriptions"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "format": "byte"}, sub["p256dh"])
 }
@@ -0,0 +1,71 @@
+package notification
+
+import (
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// TokenResult is the outcome of delivering a notification to one token.
+type TokenResult struct {
+	Token   string `json:"token"`
+	Success bool   `json:"success"`
+	// ShouldRetire marks a token the provider reported as permanently invalid
+	// (unregistered, expired subscription); it must not be retried.
+	ShouldRetire bool   `json:"shouldRetire,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// NotificationResult holds the per-token outcomes of sending one request.
+type NotificationResult struct {
+	RecipientID urn.URN       `json:"recipientId"`
+	Tokens      []TokenResult `json:"tokens"`
+}
+
+// Partition classifies each token result for the send loop's next step:
+// succeeded, failed but worth retrying later, or failed for good and to be
+// retired. ShouldRetire only applies to failures.
+func (r *NotificationResult) Partition() (succeeded, retry, retire []TokenResult) {
+	for _, tr := range r.Tokens {
+		switch {
+		case tr.Success:
+			succeeded = append(succeeded, tr)
+		case tr.ShouldRetire:
+			retire = append(retire, tr)
+		default:
+			retry = append(retry, tr)
+		}
+	}
+	return succeeded, retry, retire
+}
+
+// NotificationResultList is the outcome of sending a batch of requests.
+type NotificationResultList struct {
+	Results []*NotificationResult `json:"results"`
+}
+
+// ResultTotals counts token results by Partition bucket.
+type ResultTotals struct {
+	Succeeded int `json:"succeeded"`
+	Retry     int `json:"retry"`
+	Retire    int `json:"retire"`
+}
+
+// Total is the number of token results counted.
+func (t ResultTotals) Total() int {
+	return t.Succeeded + t.Retry + t.Retire
+}
+
+// Aggregate sums the Partition buckets across every result in the list.
+// Nil results are skipped.
+func (l *NotificationResultList) Aggregate() ResultTotals {
+	var totals ResultTotals
+	for _, r := range l.Results {
+		if r == nil {
+			continue
+		}
+		succeeded, retry, retire := r.Partition()
+		totals.Succeeded += len(succeeded)
+		totals.Retry += len(retry)
+		totals.Retire += len(retire)
+	}
+	return totals
+}
@@ -0,0 +1,64 @@
+package notification_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinywideclouds/go-platform/pkg/notification/v1"
+)
+
+func TestNotificationResult_Partition(t *testing.T) {
+	result := &notification.NotificationResult{
+		Tokens: []notification.TokenResult{
+			{Token: "ok-1", Success: true},
+			{Token: "busy", Error: "unavailable"},
+			{Token: "gone", ShouldRetire: true, Error: "unregistered"},
+			{Token: "ok-2", Success: true},
+			// ShouldRetire is ignored on success
+			{Token: "ok-3", Success: true, ShouldRetire: true},
+		},
+	}
+
+	succeeded, retry, retire := result.Partition()
+
+	tokens := func(results []notification.TokenResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Token)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"ok-1", "ok-2", "ok-3"}, tokens(succeeded))
+	assert.Equal(t, []string{"busy"}, tokens(retry))
+	assert.Equal(t, []string{"gone"}, tokens(retire))
+
+	t.Run("Empty result", func(t *testing.T) {
+		succeeded, retry, retire := (&notification.NotificationResult{}).Partition()
+		assert.Empty(t, succeeded)
+		assert.Empty(t, retry)
+		assert.Empty(t, retire)
+	})
+}
+
+func TestNotificationResultList_Aggregate(t *testing.T) {
+	list := &notification.NotificationResultList{
+		Results: []*notification.NotificationResult{
+			{Tokens: []notification.TokenResult{
+				{Token: "a", Success: true},
+				{Token: "b", Error: "timeout"},
+			}},
+			nil,
+			{Tokens: []notification.TokenResult{
+				{Token: "c", Success: true},
+				{Token: "d", ShouldRetire: true},
+				{Token: "e", ShouldRetire: true},
+			}},
+		},
+	}
+
+	totals := list.Aggregate()
+
+	assert.Equal(t, notification.ResultTotals{Succeeded: 2, Retry: 1, Retire: 2}, totals)
+	assert.Equal(t, 5, totals.Total())
+	assert.Equal(t, notification.ResultTotals{}, (&notification.NotificationResultList{}).Aggregate())
+}
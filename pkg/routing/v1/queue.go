@@ -6,13 +6,25 @@ structs and their "To/FromProto" facade functions.
 package routing
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-	// --- NEW: Protojson for JSON methods ---
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	// --- NEW: Platform imports for the facade ---
 	routingv1 "github.com/tinywideclouds/gen-platform/go/types/routing/v1"
+	"github.com/tinywideclouds/go-platform/internal/convert"
+	"github.com/tinywideclouds/go-platform/internal/jsonext"
+	"github.com/tinywideclouds/go-platform/internal/openapi"
+	"github.com/tinywideclouds/go-platform/pkg/facade/v1"
 	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
 )
 
@@ -20,6 +32,41 @@ import (
 type ConnectionInfo struct {
 	ServerInstanceID string `json:"serverInstanceId"`
 	ConnectedAt      int64  `json:"connectedAt"`
+	// Region is the deployment region of the server holding the connection,
+	// e.g. "europe-west1". Empty if unknown.
+	Region string `json:"region,omitempty"`
+	// LastSeenAt is the time of the last heartbeat, in Unix milliseconds.
+	LastSeenAt int64 `json:"lastSeenAt,omitempty"`
+}
+
+// ReapStale splits conns into those heard from within ttl of now, returned in
+// their original order, and a count of those that were not. A connection
+// that has never sent a heartbeat (LastSeenAt zero) is stale.
+func ReapStale(conns []ConnectionInfo, ttl time.Duration, now time.Time) (live []ConnectionInfo, reaped int) {
+	cutoff := now.Add(-ttl).UnixMilli()
+	for _, conn := range conns {
+		if conn.LastSeenAt == 0 || conn.LastSeenAt < cutoff {
+			reaped++
+			continue
+		}
+		live = append(live, conn)
+	}
+	return live, reaped
+}
+
+// SelectByRegion picks the first connection in the preferred region, falling
+// back to the first connection in any region. It returns false only when
+// conns is empty.
+func SelectByRegion(conns []ConnectionInfo, preferred string) (ConnectionInfo, bool) {
+	if len(conns) == 0 {
+		return ConnectionInfo{}, false
+	}
+	for _, conn := range conns {
+		if conn.Region == preferred {
+			return conn, true
+		}
+	}
+	return conns[0], true
 }
 
 // DeviceToken represents a push notification token for a user's device.
@@ -28,16 +75,10 @@ type DeviceToken struct {
 	Platform string `json:"platform"` // e.g., "ios", "android"
 }
 
-// --- NEW: Protojson marshal/unmarshal options (copied from envelope.go) ---
-var (
-	protojsonMarshalOptions = &protojson.MarshalOptions{
-		UseProtoNames:   false, // Use camelCase
-		EmitUnpopulated: false,
-	}
-	protojsonUnmarshalOptions = &protojson.UnmarshalOptions{
-		DiscardUnknown: true,
-	}
-)
+// ErrNestedEnvelope is wrapped, together with the underlying error, when a
+// message's envelope fails to parse, so callers can tell a bad envelope from
+// a bad top-level message.
+var ErrNestedEnvelope = errors.New("failed to parse nested envelope")
 
 // --- NEW: Protobuf type aliases ---
 type QueuedMessagePb = routingv1.QueuedMessagePb
@@ -50,6 +91,167 @@ type QueuedMessageListPb = routingv1.QueuedMessageListPb
 type QueuedMessage struct {
 	ID       string                 `json:"id"`
 	Envelope *secure.SecureEnvelope `json:"envelope"`
+
+	// The scheduling fields below are not part of QueuedMessagePb: ToProto
+	// drops them, and the JSON and msgpack forms carry them alongside the
+	// proto fields.
+
+	// DeliveryAttempts is the number of delivery attempts made so far.
+	DeliveryAttempts int32 `json:"deliveryAttempts,omitempty"`
+	// MaxAttempts caps DeliveryAttempts; zero means no limit.
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+	// NextAttemptAt is the earliest time, in Unix milliseconds, the message
+	// may be attempted again; zero means immediately.
+	NextAttemptAt int64 `json:"nextAttemptAt,omitempty"`
+	// Status is the message's delivery state. Legacy messages, which carry
+	// none, are StatusPending.
+	Status DeliveryStatus `json:"status,omitempty"`
+	// EnqueuedAt is when the message entered the queue, in Unix
+	// milliseconds; zero if unknown.
+	EnqueuedAt int64 `json:"enqueuedAt,omitempty"`
+}
+
+// DeliveryStatus is the delivery state of a QueuedMessage. Its JSON form is
+// the lowercase name ("pending", "delivered", ...), or "DeliveryStatus(n)"
+// for a value this version does not know, so a status added by a newer
+// producer survives a round trip.
+type DeliveryStatus int32
+
+const (
+	// StatusPending is the zero value: the message is awaiting delivery.
+	StatusPending DeliveryStatus = iota
+	// StatusDelivered means the recipient has received the message.
+	StatusDelivered
+	// StatusFailed means delivery failed permanently and will not be retried.
+	StatusFailed
+	// StatusExpired means the message outlived its TTL before delivery.
+	StatusExpired
+)
+
+var deliveryStatusNames = map[DeliveryStatus]string{
+	StatusPending:   "pending",
+	StatusDelivered: "delivered",
+	StatusFailed:    "failed",
+	StatusExpired:   "expired",
+}
+
+// String returns the status name, or "DeliveryStatus(n)" for an unknown value.
+func (s DeliveryStatus) String() string {
+	if name, ok := deliveryStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("DeliveryStatus(%d)", int32(s))
+}
+
+// MarshalText implements encoding.TextMarshaler. It writes String, so an
+// unknown value is written as "DeliveryStatus(n)" rather than failing.
+func (s DeliveryStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts the names
+// and the "DeliveryStatus(n)" form MarshalText writes for unknown values.
+// The empty string is StatusPending.
+func (s *DeliveryStatus) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = StatusPending
+		return nil
+	}
+	for status, name := range deliveryStatusNames {
+		if name == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	if digits, ok := strings.CutPrefix(string(text), "DeliveryStatus("); ok {
+		if digits, ok = strings.CutSuffix(digits, ")"); ok {
+			if n, err := strconv.ParseInt(digits, 10, 32); err == nil {
+				*s = DeliveryStatus(n)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unknown delivery status %q", text)
+}
+
+// MarkDelivered sets Status to StatusDelivered.
+func (qm *QueuedMessage) MarkDelivered() { qm.Status = StatusDelivered }
+
+// MarkFailed sets Status to StatusFailed.
+func (qm *QueuedMessage) MarkFailed() { qm.Status = StatusFailed }
+
+// MarkExpired sets Status to StatusExpired.
+func (qm *QueuedMessage) MarkExpired() { qm.Status = StatusExpired }
+
+// IsPending reports whether the message is still awaiting delivery.
+func (qm *QueuedMessage) IsPending() bool { return qm.Status == StatusPending }
+
+// WrapEnvelope returns a new pending QueuedMessage for env with a random
+// UUID as its ID and EnqueuedAt set to the current time. The message holds
+// env itself, not a copy. A nil env gives nil.
+func WrapEnvelope(env *secure.SecureEnvelope) *QueuedMessage {
+	if env == nil {
+		return nil
+	}
+	return &QueuedMessage{
+		ID:         uuid.NewString(),
+		Envelope:   env,
+		EnqueuedAt: time.Now().UnixMilli(),
+	}
+}
+
+// ToEnvelope returns a copy of the message's envelope for delivery, with
+// every envelope field preserved and the queue metadata left behind. The
+// copy shares its byte slices with the original. A nil message, or one
+// without an envelope, gives nil.
+func (qm *QueuedMessage) ToEnvelope() *secure.SecureEnvelope {
+	if qm == nil || qm.Envelope == nil {
+		return nil
+	}
+	env := *qm.Envelope
+	return &env
+}
+
+// Priority returns the envelope's priority, or 0 for a nil message or one
+// without an envelope.
+func (qm *QueuedMessage) Priority() int32 {
+	if qm == nil || qm.Envelope == nil {
+		return 0
+	}
+	return qm.Envelope.Priority
+}
+
+// MaxBackoff caps the delay ComputeBackoff returns.
+const MaxBackoff = 24 * time.Hour
+
+// ShouldRetry reports whether the delivery worker should attempt qm at now:
+// it is still pending, has attempts left under MaxAttempts and NextAttemptAt
+// has passed.
+func (qm *QueuedMessage) ShouldRetry(now time.Time) bool {
+	if !qm.IsPending() {
+		return false
+	}
+	if qm.MaxAttempts > 0 && qm.DeliveryAttempts >= qm.MaxAttempts {
+		return false
+	}
+	return now.UnixMilli() >= qm.NextAttemptAt
+}
+
+// ComputeBackoff returns the delay before the next attempt: base after the
+// first failed attempt, doubling with each further attempt, capped at
+// MaxBackoff. Set NextAttemptAt to now plus this delay.
+func (qm *QueuedMessage) ComputeBackoff(base time.Duration) time.Duration {
+	if base <= 0 || qm.DeliveryAttempts <= 1 {
+		return min(max(base, 0), MaxBackoff)
+	}
+	backoff := base
+	for range qm.DeliveryAttempts - 1 {
+		backoff *= 2
+		if backoff >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return backoff
 }
 
 // ToProto converts the idiomatic Go struct into its Protobuf representation.
@@ -63,6 +265,14 @@ func ToProto(native *QueuedMessage) *QueuedMessagePb {
 	}
 }
 
+// ToProtoMessage implements facade.Protoer, returning ToProto(&qm) as a
+// *QueuedMessagePb.
+func (qm QueuedMessage) ToProtoMessage() proto.Message {
+	return ToProto(&qm)
+}
+
+var _ facade.Protoer = QueuedMessage{}
+
 // FromProto converts the Protobuf representation into the idiomatic Go struct.
 func FromProto(proto *QueuedMessagePb) (*QueuedMessage, error) {
 	if proto == nil {
@@ -71,7 +281,7 @@ func FromProto(proto *QueuedMessagePb) (*QueuedMessage, error) {
 
 	nativeEnvelope, err := secure.FromProto(proto.Envelope)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse nested envelope from proto: %w", err)
+		return nil, fmt.Errorf("%w from proto: %w", ErrNestedEnvelope, err)
 	}
 
 	return &QueuedMessage{
@@ -84,24 +294,130 @@ func FromProto(proto *QueuedMessagePb) (*QueuedMessage, error) {
 
 // MarshalJSON implements the json.Marshaler interface.
 func (qm QueuedMessage) MarshalJSON() ([]byte, error) {
-	protoPb := ToProto(&qm)
-	return protojsonMarshalOptions.Marshal(protoPb)
+	return qm.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options, e.g.
+// UseProtoNames and EmitUnpopulated for a debug endpoint.
+//
+// The envelope is written by its own facade rather than through
+// QueuedMessagePb, so the envelope fields the proto does not carry survive.
+func (qm QueuedMessage) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	data, err := opts.Marshal(&QueuedMessagePb{Id: qm.ID})
+	if err != nil {
+		return nil, err
+	}
+	ext := queuedMessageExtJSON{
+		DeliveryAttempts: qm.DeliveryAttempts,
+		MaxAttempts:      qm.MaxAttempts,
+		NextAttemptAt:    qm.NextAttemptAt,
+		Status:           qm.Status,
+		EnqueuedAt:       qm.EnqueuedAt,
+	}
+	if qm.Envelope != nil {
+		if ext.Envelope, err = qm.Envelope.MarshalJSONWith(opts); err != nil {
+			return nil, err
+		}
+	}
+	return jsonext.MergeWith(data, ext, opts)
+}
+
+// queuedMessageExtJSON holds the QueuedMessage JSON members not written via
+// QueuedMessagePb: the envelope, the scheduling fields and the status.
+type queuedMessageExtJSON struct {
+	Envelope         json.RawMessage `json:"envelope,omitempty"`
+	DeliveryAttempts int32           `json:"deliveryAttempts,omitempty"`
+	MaxAttempts      int32           `json:"maxAttempts,omitempty"`
+	NextAttemptAt    int64           `json:"nextAttemptAt,omitempty"`
+	Status           DeliveryStatus  `json:"status,omitempty"`
+	EnqueuedAt       int64           `json:"enqueuedAt,omitempty"`
+}
+
+// MarshalCanonical returns the JSON form with sorted keys and no
+// insignificant whitespace, so equal values always give identical bytes.
+// Use it for hashing and signing rather than MarshalJSON.
+func (qm QueuedMessage) MarshalCanonical() ([]byte, error) {
+	data, err := qm.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jsonext.Canonical(data)
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (qm *QueuedMessage) UnmarshalJSON(data []byte) error {
-	var protoPb QueuedMessagePb
-	if err := protojsonUnmarshalOptions.Unmarshal(data, &protoPb); err != nil {
+	var wire struct {
+		ID string `json:"id"`
+		queuedMessageExtJSON
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
 		return err
 	}
-	native, err := FromProto(&protoPb)
-	if err != nil {
+	if err := jsonext.Unmarshal(data, &wire.queuedMessageExtJSON); err != nil {
+		return err
+	}
+	native := QueuedMessage{
+		ID:               wire.ID,
+		DeliveryAttempts: wire.DeliveryAttempts,
+		MaxAttempts:      wire.MaxAttempts,
+		NextAttemptAt:    wire.NextAttemptAt,
+		Status:           wire.Status,
+		EnqueuedAt:       wire.EnqueuedAt,
+	}
+	if len(wire.Envelope) > 0 && string(wire.Envelope) != "null" {
+		native.Envelope = &secure.SecureEnvelope{}
+		if err := native.Envelope.UnmarshalJSON(wire.Envelope); err != nil {
+			return fmt.Errorf("%w: %w", ErrNestedEnvelope, err)
+		}
+	}
+	*qm = native
+	return nil
+}
+
+// --- Msgpack Methods ---
+
+// queuedMessageMsgpack is the msgpack wire form of QueuedMessage: a map
+// keyed like the JSON form, with the envelope written by its own
+// MarshalMsgpack.
+type queuedMessageMsgpack struct {
+	DeliveryAttempts int32                  `msgpack:"deliveryAttempts,omitempty"`
+	EnqueuedAt       int64                  `msgpack:"enqueuedAt,omitempty"`
+	Envelope         *secure.SecureEnvelope `msgpack:"envelope,omitempty"`
+	ID               string                 `msgpack:"id,omitempty"`
+	MaxAttempts      int32                  `msgpack:"maxAttempts,omitempty"`
+	NextAttemptAt    int64                  `msgpack:"nextAttemptAt,omitempty"`
+	Status           DeliveryStatus         `msgpack:"status,omitempty"`
+}
+
+// MarshalMsgpack implements the msgpack.Marshaler interface. The map is keyed
+// like the JSON form, with the envelope's byte fields as raw msgpack bin
+// values.
+func (qm QueuedMessage) MarshalMsgpack() ([]byte, error) {
+	return msgpack.Marshal(queuedMessageMsgpack{
+		DeliveryAttempts: qm.DeliveryAttempts,
+		EnqueuedAt:       qm.EnqueuedAt,
+		Envelope:         qm.Envelope,
+		ID:               qm.ID,
+		MaxAttempts:      qm.MaxAttempts,
+		NextAttemptAt:    qm.NextAttemptAt,
+		Status:           qm.Status,
+	})
+}
+
+// UnmarshalMsgpack implements the msgpack.Unmarshaler interface.
+func (qm *QueuedMessage) UnmarshalMsgpack(data []byte) error {
+	var wire queuedMessageMsgpack
+	if err := msgpack.Unmarshal(data, &wire); err != nil {
 		return err
 	}
-	if native != nil {
-		*qm = *native
-	} else {
-		*qm = QueuedMessage{}
+	*qm = QueuedMessage{
+		ID:               wire.ID,
+		Envelope:         wire.Envelope,
+		DeliveryAttempts: wire.DeliveryAttempts,
+		MaxAttempts:      wire.MaxAttempts,
+		NextAttemptAt:    wire.NextAttemptAt,
+		Status:           wire.Status,
+		EnqueuedAt:       wire.EnqueuedAt,
 	}
 	return nil
 }
@@ -118,55 +434,139 @@ func ListToProto(native *QueuedMessageList) *QueuedMessageListPb {
 	if native == nil {
 		return nil
 	}
-	protoMessages := make([]*QueuedMessagePb, len(native.Messages))
-	for i, msg := range native.Messages {
-		protoMessages[i] = ToProto(msg)
-	}
 	return &QueuedMessageListPb{
-		Messages: protoMessages,
+		Messages: convert.List(native.Messages, ToProto),
 	}
 }
 
 // ListFromProto converts the Protobuf list into the idiomatic Go struct.
 func ListFromProto(proto *QueuedMessageListPb) (*QueuedMessageList, error) {
+	return ListFromProtoContext(context.Background(), proto)
+}
+
+// ListFromProtoContext is ListFromProto for large lists: it checks ctx
+// periodically while converting and returns ctx.Err() once it is done.
+func ListFromProtoContext(ctx context.Context, proto *QueuedMessageListPb) (*QueuedMessageList, error) {
 	if proto == nil {
 		return nil, nil
 	}
-	nativeMessages := make([]*QueuedMessage, len(proto.Messages))
-	var err error
-	for i, pMsg := range proto.Messages {
-		nativeMessages[i], err = FromProto(pMsg)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse message at index %d: %w", i, err)
+	nativeMessages, err := convert.ListErrContext(ctx, proto.Messages, FromProto)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to parse message %w", err)
 	}
 	return &QueuedMessageList{
 		Messages: nativeMessages,
 	}, nil
 }
 
+// MergeLists concatenates the messages of lists in argument order, e.g. when
+// draining several shards. Nil lists are skipped. The result shares its
+// messages with the inputs.
+func MergeLists(lists ...*QueuedMessageList) *QueuedMessageList {
+	total := 0
+	for _, list := range lists {
+		if list != nil {
+			total += len(list.Messages)
+		}
+	}
+	merged := &QueuedMessageList{}
+	if total == 0 {
+		return merged
+	}
+	merged.Messages = make([]*QueuedMessage, 0, total)
+	for _, list := range lists {
+		if list != nil {
+			merged.Messages = append(merged.Messages, list.Messages...)
+		}
+	}
+	return merged
+}
+
+// MergeListsDedup is MergeLists keeping only the first message with each ID,
+// for shards that may redeliver the same message. Nil messages are dropped.
+func MergeListsDedup(lists ...*QueuedMessageList) *QueuedMessageList {
+	merged := MergeLists(lists...)
+	seen := make(map[string]struct{}, len(merged.Messages))
+	deduped := merged.Messages[:0]
+	for _, msg := range merged.Messages {
+		if msg == nil {
+			continue
+		}
+		if _, dup := seen[msg.ID]; dup {
+			continue
+		}
+		seen[msg.ID] = struct{}{}
+		deduped = append(deduped, msg)
+	}
+	if len(deduped) == 0 {
+		deduped = nil
+	}
+	merged.Messages = deduped
+	return merged
+}
+
 // --- NEW: JSON Methods (List) ---
 
 // MarshalJSON implements the json.Marshaler interface.
 func (qml QueuedMessageList) MarshalJSON() ([]byte, error) {
-	protoPb := ListToProto(&qml)
-	return protojsonMarshalOptions.Marshal(protoPb)
+	return qml.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options,
+// applied to every message. Like QueuedMessage, the list is built from each
+// message's own JSON.
+func (qml QueuedMessageList) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	return jsonext.MarshalList("messages", qml.Messages, opts)
+}
+
+// queuedMessageListJSON is the wire form of QueuedMessageList.
+type queuedMessageListJSON struct {
+	Messages []json.RawMessage `json:"messages,omitempty"`
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (qml *QueuedMessageList) UnmarshalJSON(data []byte) error {
-	var protoPb QueuedMessageListPb
-	if err := protojsonUnmarshalOptions.Unmarshal(data, &protoPb); err != nil {
+	var wire queuedMessageListJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
 		return err
 	}
-	native, err := ListFromProto(&protoPb)
-	if err != nil {
-		return err
+	native := QueuedMessageList{}
+	if len(wire.Messages) > 0 {
+		native.Messages = make([]*QueuedMessage, len(wire.Messages))
 	}
-	if native != nil {
-		*qml = *native
-	} else {
-		*qml = QueuedMessageList{}
+	for i, raw := range wire.Messages {
+		if string(raw) == "null" {
+			continue
+		}
+		msg := &QueuedMessage{}
+		if err := msg.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("failed to parse message at index %d: %w", i, err)
+		}
+		native.Messages[i] = msg
 	}
+	*qml = native
 	return nil
 }
+
+// --- Schema ---
+
+// OpenAPISchema returns the OpenAPI 3.1 schema object for the JSON form of
+// QueuedMessage, with the envelope schema inlined.
+func (qm QueuedMessage) OpenAPISchema() (map[string]any, error) {
+	schema := openapi.Schema((&QueuedMessagePb{}).ProtoReflect().Descriptor())
+	envelope, err := secure.SecureEnvelope{}.OpenAPISchema()
+	if err != nil {
+		return nil, err
+	}
+	props := openapi.Properties(schema)
+	props["envelope"] = envelope
+	props["deliveryAttempts"] = map[string]any{"type": "integer", "format": "int32"}
+	props["maxAttempts"] = map[string]any{"type": "integer", "format": "int32"}
+	props["nextAttemptAt"] = map[string]any{"type": "integer", "format": "int64"}
+	props["status"] = map[string]any{"type": "string", "enum": []any{"pending", "delivered", "failed", "expired"}}
+	props["enqueuedAt"] = map[string]any{"type": "integer", "format": "int64"}
+	return schema, nil
+}
@@ -5,12 +5,16 @@ Description: NEW test file to validate the "QueuedMessage" facade.
 package routing_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	// Import the native packages we are testing and using
 	"github.com/tinywideclouds/go-platform/pkg/net/v1"
@@ -29,6 +33,7 @@ func newTestEnvelope(t *testing.T) *secure.SecureEnvelope {
 		EncryptedData:         []byte{1, 2, 3},
 		EncryptedSymmetricKey: []byte{4, 5, 6},
 		Signature:             []byte{7, 8, 9},
+		SchemaVersion:         secure.CurrentSchemaVersion,
 	}
 }
 
@@ -70,6 +75,40 @@ func TestQueuedMessage_Proto_RoundTrip(t *testing.T) {
 
 }
 
+func TestQueuedMessage_NestedEnvelopeError(t *testing.T) {
+	badEnvelope := secure.ToProto(newTestEnvelope(t))
+	badEnvelope.RecipientId = "urn:sm:user"
+
+	t.Run("FromProto", func(t *testing.T) {
+		_, err := routing.FromProto(&routing.QueuedMessagePb{Id: "msg-1", Envelope: badEnvelope})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, routing.ErrNestedEnvelope)
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat, "the underlying error is kept")
+		assert.Contains(t, err.Error(), "failed to parse nested envelope from proto")
+	})
+
+	t.Run("ListFromProto", func(t *testing.T) {
+		list := &routing.QueuedMessageListPb{Messages: []*routing.QueuedMessagePb{{Id: "msg-1", Envelope: badEnvelope}}}
+		_, err := routing.ListFromProto(list)
+		assert.ErrorIs(t, err, routing.ErrNestedEnvelope)
+	})
+
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		var qm routing.QueuedMessage
+		err := json.Unmarshal([]byte(`{"id":"msg-1","envelope":{"recipientId":"urn:sm:user"}}`), &qm)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, routing.ErrNestedEnvelope)
+		assert.Contains(t, err.Error(), "failed to parse nested envelope")
+	})
+
+	t.Run("Top-level errors are not nested", func(t *testing.T) {
+		var qm routing.QueuedMessage
+		err := json.Unmarshal([]byte(`{"id":1}`), &qm)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, routing.ErrNestedEnvelope)
+	})
+}
+
 func TestQueuedMessageList_Proto_RoundTrip(t *testing.T) {
 	// Arrange
 	nativeList := &routing.QueuedMessageList{
@@ -112,6 +151,7 @@ func TestQueuedMessage_JSON_RoundTrip(t *testing.T) {
 			"encryptedData": "AQID",
 			"encryptedSymmetricKey": "BAUG",
 			"priority": 0,
+			"schemaVersion": 1,
 			"signature": "BwgJ"
 		}
 	}`
@@ -131,3 +171,405 @@ func TestQueuedMessage_JSON_RoundTrip(t *testing.T) {
 		assert.Equal(t, nativeMsg, &resultStruct)
 	})
 }
+
+func TestQueuedMessageList_JSON_NilAndEmpty(t *testing.T) {
+	withNil := routing.QueuedMessageList{Messages: []*routing.QueuedMessage{nil}}
+	jsonBytes, err := json.Marshal(withNil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"messages":[null]}`, string(jsonBytes))
+
+	var resultList routing.QueuedMessageList
+	require.NoError(t, json.Unmarshal(jsonBytes, &resultList))
+	assert.Equal(t, withNil, resultList)
+
+	jsonBytes, err = json.Marshal(routing.QueuedMessageList{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(jsonBytes))
+}
+
+func TestQueuedMessage_OpenAPISchema(t *testing.T) {
+	schema, err := routing.QueuedMessage{}.OpenAPISchema()
+	require.NoError(t, err)
+
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string"}, props["id"])
+	envelope := props["envelope"].(map[string]any)
+	assert.Contains(t, envelope["properties"], "encryptedSymmetricKey")
+}
+
+// cancelAfter is a context that reports context.Canceled after n checks.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestListFromProtoContext(t *testing.T) {
+	nativeList := &routing.QueuedMessageList{}
+	for range 500 {
+		nativeList.Messages = append(nativeList.Messages, &routing.QueuedMessage{ID: uuid.NewString(), Envelope: newTestEnvelope(t)})
+	}
+	protoListPb := routing.ListToProto(nativeList)
+
+	t.Run("Completes", func(t *testing.T) {
+		got, err := routing.ListFromProtoContext(context.Background(), protoListPb)
+		require.NoError(t, err)
+		assert.Equal(t, nativeList, got)
+	})
+
+	t.Run("Cancelled mid-conversion", func(t *testing.T) {
+		got, err := routing.ListFromProtoContext(&cancelAfter{Context: context.Background(), n: 2}, protoListPb)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, context.Canceled, err, "the context error should not be wrapped")
+		assert.Nil(t, got)
+	})
+}
+
+func TestQueuedMessage_Msgpack_RoundTrip(t *testing.T) {
+	original := &routing.QueuedMessage{ID: uuid.NewString(), Envelope: newTestEnvelope(t)}
+
+	data, err := msgpack.Marshal(original)
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, msgpack.Unmarshal(data, &raw))
+	assert.Equal(t, original.ID, raw["id"])
+	require.IsType(t, map[string]any{}, raw["envelope"])
+	assert.Equal(t, []byte{7, 8, 9}, raw["envelope"].(map[string]any)["signature"])
+
+	var got routing.QueuedMessage
+	require.NoError(t, msgpack.Unmarshal(data, &got))
+	assert.Equal(t, original, &got)
+}
+
+func TestQueuedMessage_EnvelopeExtensionFields(t *testing.T) {
+	env := newTestEnvelope(t)
+	env.AssociatedData = []byte("aad")
+	original := &routing.QueuedMessage{ID: uuid.NewString(), Envelope: env}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+	var fromJSON routing.QueuedMessage
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.Equal(t, original, &fromJSON)
+
+	data, err = msgpack.Marshal(original)
+	require.NoError(t, err)
+	var fromMsgpack routing.QueuedMessage
+	require.NoError(t, msgpack.Unmarshal(data, &fromMsgpack))
+	assert.Equal(t, original, &fromMsgpack)
+
+	list := routing.QueuedMessageList{Messages: []*routing.QueuedMessage{original, {ID: "no-envelope"}}}
+	data, err = json.Marshal(list)
+	require.NoError(t, err)
+	var fromListJSON routing.QueuedMessageList
+	require.NoError(t, json.Unmarshal(data, &fromListJSON))
+	assert.Equal(t, list, fromListJSON)
+}
+
+func TestQueuedMessage_ShouldRetry(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+
+	testCases := []struct {
+		name     string
+		msg      routing.QueuedMessage
+		expected bool
+	}{
+		{name: "Never attempted", msg: routing.QueuedMessage{MaxAttempts: 3}, expected: true},
+		{name: "Due", msg: routing.QueuedMessage{DeliveryAttempts: 1, MaxAttempts: 3, NextAttemptAt: now.UnixMilli()}, expected: true},
+		{name: "Not yet due", msg: routing.QueuedMessage{DeliveryAttempts: 1, MaxAttempts: 3, NextAttemptAt: now.Add(time.Second).UnixMilli()}, expected: false},
+		{name: "Attempts exhausted", msg: routing.QueuedMessage{DeliveryAttempts: 3, MaxAttempts: 3}, expected: false},
+		{name: "No limit", msg: routing.QueuedMessage{DeliveryAttempts: 100}, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.msg.ShouldRetry(now))
+		})
+	}
+}
+
+func TestQueuedMessage_ComputeBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	expected := []time.Duration{base, base, 2 * base, 4 * base, 8 * base, 16 * base}
+	for attempts, want := range expected {
+		msg := routing.QueuedMessage{DeliveryAttempts: int32(attempts)}
+		assert.Equal(t, want, msg.ComputeBackoff(base), "after %d attempts", attempts)
+	}
+
+	msg := routing.QueuedMessage{DeliveryAttempts: 1000}
+	assert.Equal(t, routing.MaxBackoff, msg.ComputeBackoff(base), "backoff is capped")
+	assert.Zero(t, msg.ComputeBackoff(0))
+}
+
+func TestQueuedMessage_WrapEnvelope(t *testing.T) {
+	env := newTestEnvelope(t)
+	env.AssociatedData = []byte("aad")
+	env.ContentType = secure.ContentTypeText
+
+	before := time.Now().UnixMilli()
+	qm := routing.WrapEnvelope(env)
+	require.NotNil(t, qm)
+	_, err := uuid.Parse(qm.ID)
+	assert.NoError(t, err, "ID is a UUID")
+	assert.Same(t, env, qm.Envelope)
+	assert.GreaterOrEqual(t, qm.EnqueuedAt, before)
+	assert.LessOrEqual(t, qm.EnqueuedAt, time.Now().UnixMilli())
+	assert.True(t, qm.IsPending())
+
+	assert.NotEqual(t, qm.ID, routing.WrapEnvelope(env).ID, "every wrap gets a fresh ID")
+	assert.Nil(t, routing.WrapEnvelope(nil))
+}
+
+func TestQueuedMessage_ToEnvelope(t *testing.T) {
+	env := newTestEnvelope(t)
+	env.AssociatedData = []byte("aad")
+	env.ContentType = secure.ContentTypeText
+	env.Priority = 7
+	qm := &routing.QueuedMessage{ID: "msg-1", Envelope: env, DeliveryAttempts: 2, EnqueuedAt: 1}
+
+	got := qm.ToEnvelope()
+	require.NotNil(t, got)
+	assert.Equal(t, env, got, "every envelope field is preserved")
+	assert.NotSame(t, env, got)
+
+	got.Priority = 0
+	assert.Equal(t, int32(7), qm.Envelope.Priority, "the queued envelope is unaffected")
+
+	t.Run("Round trip through WrapEnvelope", func(t *testing.T) {
+		assert.Equal(t, env, routing.WrapEnvelope(env).ToEnvelope())
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var nilMsg *routing.QueuedMessage
+		assert.Nil(t, nilMsg.ToEnvelope())
+		assert.Nil(t, (&routing.QueuedMessage{ID: "empty"}).ToEnvelope())
+	})
+}
+
+func TestQueuedMessage_SchedulingFields_RoundTrip(t *testing.T) {
+	original := &routing.QueuedMessage{
+		ID:               "scheduled",
+		Envelope:         newTestEnvelope(t),
+		DeliveryAttempts: 2,
+		MaxAttempts:      5,
+		NextAttemptAt:    1_700_000_000_123,
+		EnqueuedAt:       1_699_999_000_000,
+	}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"nextAttemptAt":1700000000123`)
+	var fromJSON routing.QueuedMessage
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.Equal(t, original, &fromJSON)
+
+	data, err = msgpack.Marshal(original)
+	require.NoError(t, err)
+	var fromMsgpack routing.QueuedMessage
+	require.NoError(t, msgpack.Unmarshal(data, &fromMsgpack))
+	assert.Equal(t, original, &fromMsgpack)
+
+	data, err = original.MarshalJSONWith(protojson.MarshalOptions{UseProtoNames: true})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"next_attempt_at":1700000000123`)
+	assert.Contains(t, string(data), `"recipient_id":"urn:sm:user:recipient-bob"`)
+	var fromProtoNames routing.QueuedMessage
+	require.NoError(t, json.Unmarshal(data, &fromProtoNames))
+	assert.Equal(t, original, &fromProtoNames)
+}
+
+func TestQueuedMessage_Status(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		for _, status := range []routing.DeliveryStatus{routing.StatusPending, routing.StatusDelivered, routing.StatusFailed, routing.StatusExpired} {
+			original := &routing.QueuedMessage{ID: "m1", Envelope: newTestEnvelope(t), Status: status}
+
+			data, err := json.Marshal(original)
+			require.NoError(t, err)
+			if status == routing.StatusPending {
+				assert.NotContains(t, string(data), `"status"`)
+			} else {
+				assert.Contains(t, string(data), `"status":"`+status.String()+`"`)
+			}
+			var fromJSON routing.QueuedMessage
+			require.NoError(t, json.Unmarshal(data, &fromJSON))
+			assert.Equal(t, original, &fromJSON)
+
+			data, err = msgpack.Marshal(original)
+			require.NoError(t, err)
+			var fromMsgpack routing.QueuedMessage
+			require.NoError(t, msgpack.Unmarshal(data, &fromMsgpack))
+			assert.Equal(t, original, &fromMsgpack)
+		}
+	})
+
+	t.Run("Legacy message is pending", func(t *testing.T) {
+		var legacy routing.QueuedMessage
+		require.NoError(t, json.Unmarshal([]byte(`{"id":"old"}`), &legacy))
+		assert.Equal(t, routing.StatusPending, legacy.Status)
+		assert.True(t, legacy.IsPending())
+
+		fromProto, err := routing.FromProto(&routing.QueuedMessagePb{Id: "old"})
+		require.NoError(t, err)
+		assert.Equal(t, routing.StatusPending, fromProto.Status)
+	})
+
+	t.Run("Unknown status", func(t *testing.T) {
+		var msg routing.QueuedMessage
+		assert.Error(t, json.Unmarshal([]byte(`{"id":"m","status":"lost"}`), &msg))
+		assert.Error(t, json.Unmarshal([]byte(`{"id":"m","status":"DeliveryStatus(x)"}`), &msg))
+		assert.Equal(t, "DeliveryStatus(42)", routing.DeliveryStatus(42).String())
+
+		original := routing.QueuedMessage{ID: "m", Status: routing.DeliveryStatus(42)}
+		data, err := json.Marshal(original)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"status":"DeliveryStatus(42)"`)
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, original, msg)
+	})
+
+	t.Run("Helpers", func(t *testing.T) {
+		now := time.Now()
+		msg := &routing.QueuedMessage{ID: "m"}
+		assert.True(t, msg.ShouldRetry(now))
+
+		msg.MarkDelivered()
+		assert.Equal(t, routing.StatusDelivered, msg.Status)
+		assert.False(t, msg.IsPending())
+		assert.False(t, msg.ShouldRetry(now), "delivered messages are not retried")
+
+		msg.MarkFailed()
+		assert.Equal(t, routing.StatusFailed, msg.Status)
+		assert.False(t, msg.ShouldRetry(now))
+
+		msg.MarkExpired()
+		assert.Equal(t, routing.StatusExpired, msg.Status)
+		assert.False(t, msg.ShouldRetry(now))
+	})
+}
+
+func TestMergeLists(t *testing.T) {
+	msg := func(id string) *routing.QueuedMessage {
+		return &routing.QueuedMessage{ID: id, Envelope: newTestEnvelope(t)}
+	}
+	ids := func(list *routing.QueuedMessageList) []string {
+		var out []string
+		for _, m := range list.Messages {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	shardA := &routing.QueuedMessageList{Messages: []*routing.QueuedMessage{msg("a1"), msg("a2")}}
+	shardB := &routing.QueuedMessageList{Messages: []*routing.QueuedMessage{msg("b1"), msg("a2")}}
+	shardC := &routing.QueuedMessageList{Messages: []*routing.QueuedMessage{msg("c1")}}
+
+	t.Run("Concatenates in argument order", func(t *testing.T) {
+		merged := routing.MergeLists(shardA, nil, shardB, shardC)
+		assert.Equal(t, []string{"a1", "a2", "b1", "a2", "c1"}, ids(merged))
+		assert.Same(t, shardA.Messages[0], merged.Messages[0])
+	})
+
+	t.Run("Dedup keeps the first occurrence", func(t *testing.T) {
+		merged := routing.MergeListsDedup(shardA, nil, shardB, shardC)
+		assert.Equal(t, []string{"a1", "a2", "b1", "c1"}, ids(merged))
+		assert.Same(t, shardA.Messages[1], merged.Messages[1])
+		assert.Len(t, shardB.Messages, 2, "inputs are not modified")
+	})
+
+	t.Run("Nothing to merge", func(t *testing.T) {
+		assert.Empty(t, routing.MergeLists().Messages)
+		assert.Empty(t, routing.MergeLists(nil, nil).Messages)
+		assert.Empty(t, routing.MergeListsDedup(nil).Messages)
+	})
+}
+
+func TestSelectByRegion(t *testing.T) {
+	conns := []routing.ConnectionInfo{
+		{ServerInstanceID: "us-1", Region: "us-central1"},
+		{ServerInstanceID: "eu-1", Region: "europe-west1"},
+		{ServerInstanceID: "eu-2", Region: "europe-west1"},
+	}
+
+	t.Run("Preferred region", func(t *testing.T) {
+		conn, ok := routing.SelectByRegion(conns, "europe-west1")
+		require.True(t, ok)
+		assert.Equal(t, "eu-1", conn.ServerInstanceID)
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		conn, ok := routing.SelectByRegion(conns, "asia-east1")
+		require.True(t, ok)
+		assert.Equal(t, "us-1", conn.ServerInstanceID)
+	})
+
+	t.Run("No connections", func(t *testing.T) {
+		_, ok := routing.SelectByRegion(nil, "europe-west1")
+		assert.False(t, ok)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(conns[1])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"serverInstanceId":"eu-1","connectedAt":0,"region":"europe-west1"}`, string(data))
+
+		var legacy routing.ConnectionInfo
+		require.NoError(t, json.Unmarshal([]byte(`{"serverInstanceId":"old","connectedAt":1}`), &legacy))
+		assert.Empty(t, legacy.Region)
+	})
+}
+
+func TestReapStale(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	ttl := 30 * time.Second
+	fresh := func(id string, age time.Duration) routing.ConnectionInfo {
+		return routing.ConnectionInfo{ServerInstanceID: id, LastSeenAt: now.Add(-age).UnixMilli()}
+	}
+
+	t.Run("All live", func(t *testing.T) {
+		conns := []routing.ConnectionInfo{fresh("a", 0), fresh("b", ttl)}
+		live, reaped := routing.ReapStale(conns, ttl, now)
+		assert.Equal(t, conns, live)
+		assert.Zero(t, reaped)
+	})
+
+	t.Run("All stale", func(t *testing.T) {
+		conns := []routing.ConnectionInfo{fresh("a", ttl+time.Millisecond), {ServerInstanceID: "never-seen"}}
+		live, reaped := routing.ReapStale(conns, ttl, now)
+		assert.Empty(t, live)
+		assert.Equal(t, 2, reaped)
+	})
+
+	t.Run("Mixed", func(t *testing.T) {
+		conns := []routing.ConnectionInfo{fresh("a", time.Minute), fresh("b", time.Second), fresh("c", time.Hour), fresh("d", 0)}
+		live, reaped := routing.ReapStale(conns, ttl, now)
+		require.Len(t, live, 2)
+		assert.Equal(t, "b", live[0].ServerInstanceID)
+		assert.Equal(t, "d", live[1].ServerInstanceID)
+		assert.Equal(t, 2, reaped)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		conn := fresh("a", 0)
+		data, err := json.Marshal(conn)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"lastSeenAt":1700000000000`)
+		var got routing.ConnectionInfo
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, conn, got)
+	})
+}
+
+func TestQueuedMessage_Priority(t *testing.T) {
+	env := newTestEnvelope(t)
+	env.Priority = 7
+	assert.Equal(t, int32(7), (&routing.QueuedMessage{ID: "m", Envelope: env}).Priority())
+	assert.Equal(t, int32(0), (&routing.QueuedMessage{ID: "no-envelope"}).Priority())
+	assert.Equal(t, int32(0), (*routing.QueuedMessage)(nil).Priority())
+}
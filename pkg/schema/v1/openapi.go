@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/routing/v1"
+	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+	name "github.com/tinywideclouds/go-platform/pkg/user/v1"
+)
+
+// componentRef returns an OpenAPI reference to the named component schema.
+func componentRef(component string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + component}
+}
+
+// OpenAPIComponents returns the OpenAPI 3.1 components.schemas entries for
+// the domain types, keyed by component name: User, PublicKeys,
+// SecureEnvelope, QueuedMessage and URN. Byte fields are "format": "byte"
+// strings. URN fields and the queued message's envelope are $refs to the URN
+// and SecureEnvelope components, so merge the map whole into a spec; the
+// User id may also be null, for a zero ID. Each call returns a new map.
+func OpenAPIComponents() map[string]any {
+	user := must(name.User{}.OpenAPISchema())
+	user["properties"].(map[string]any)["id"] = map[string]any{
+		"anyOf": []any{componentRef("URN"), map[string]any{"type": "null"}},
+	}
+
+	envelope := must(secure.SecureEnvelope{}.OpenAPISchema())
+	envelope["properties"].(map[string]any)["recipientId"] = componentRef("URN")
+	envelope["required"] = []string{"recipientId"}
+
+	queued := must(routing.QueuedMessage{}.OpenAPISchema())
+	queued["properties"].(map[string]any)["envelope"] = componentRef("SecureEnvelope")
+
+	return map[string]any{
+		"User":           user,
+		"PublicKeys":     must(keys.PublicKeys{}.OpenAPISchema()),
+		"SecureEnvelope": envelope,
+		"QueuedMessage":  queued,
+		"URN": map[string]any{
+			"type":        "string",
+			"pattern":     urnPattern,
+			"description": "A URN in its String form, urn:<namespace>:<entityType>:<entityId>.",
+			"examples":    []string{"urn:sm:user:user-123"},
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinywideclouds/go-platform/pkg/schema/v1"
+)
+
+// refs collects every "$ref" in v.
+func refs(v any) []string {
+	var out []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				out = append(out, ref)
+			}
+			out = append(out, refs(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			out = append(out, refs(value)...)
+		}
+	}
+	return out
+}
+
+func TestOpenAPIComponents(t *testing.T) {
+	components := schema.OpenAPIComponents()
+	assert.ElementsMatch(t, []string{"User", "PublicKeys", "SecureEnvelope", "QueuedMessage", "URN"}, slices.Collect(maps.Keys(components)))
+
+	t.Run("Each schema round-trips through JSON", func(t *testing.T) {
+		for component, s := range components {
+			data, err := json.Marshal(s)
+			require.NoError(t, err, component)
+			var decoded map[string]any
+			require.NoError(t, json.Unmarshal(data, &decoded), component)
+			again, err := json.Marshal(decoded)
+			require.NoError(t, err, component)
+			assert.JSONEq(t, string(data), string(again), component)
+		}
+	})
+
+	// Work on the JSON form from here, as a spec would.
+	data, err := json.Marshal(components)
+	require.NoError(t, err)
+	var decoded map[string]map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	props := func(component string) map[string]any {
+		return decoded[component]["properties"].(map[string]any)
+	}
+
+	t.Run("References resolve", func(t *testing.T) {
+		var found []string
+		for _, s := range decoded {
+			found = append(found, refs(s)...)
+		}
+		assert.NotEmpty(t, found)
+		for _, ref := range found {
+			component, ok := strings.CutPrefix(ref, "#/components/schemas/")
+			require.True(t, ok, ref)
+			assert.Contains(t, decoded, component, ref)
+		}
+		assert.Equal(t, map[string]any{"anyOf": []any{
+			map[string]any{"$ref": "#/components/schemas/URN"},
+			map[string]any{"type": "null"},
+		}}, props("User")["id"])
+		assert.Equal(t, map[string]any{"$ref": "#/components/schemas/URN"}, props("SecureEnvelope")["recipientId"])
+		assert.Equal(t, map[string]any{"$ref": "#/components/schemas/SecureEnvelope"}, props("QueuedMessage")["envelope"])
+	})
+
+	t.Run("Byte fields", func(t *testing.T) {
+		for component, field := range map[string]string{"PublicKeys": "encKey", "SecureEnvelope": "encryptedData"} {
+			assert.Equal(t, map[string]any{"type": "string", "format": "byte"}, props(component)[field], component)
+		}
+	})
+
+	t.Run("URN pattern", func(t *testing.T) {
+		pattern := regexp.MustCompile(decoded["URN"]["pattern"].(string))
+		for _, s := range []string{"urn:sm:user:user-123", "urn:sm:thread:t1/message:m2", "urn:lookup:ext:a:b"} {
+			assert.True(t, pattern.MatchString(s), s)
+		}
+		for _, s := range []string{"user-123", "urn:sm:user", "urn::user:x", "http://example.com"} {
+			assert.False(t, pattern.MatchString(s), s)
+		}
+	})
+
+	t.Run("Fresh map per call", func(t *testing.T) {
+		components["User"] = nil
+		assert.NotNil(t, schema.OpenAPIComponents()["User"])
+	})
+}
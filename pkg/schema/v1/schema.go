@@ -0,0 +1,112 @@
+// Package schema publishes JSON Schema documents for the v1 domain types, for
+// gateways that validate request bodies before they reach a facade, and the
+// matching OpenAPI component schemas (see OpenAPIComponents).
+//
+// The documents are derived from each type's OpenAPISchema, so they follow
+// the Go types as fields are added. Byte fields are base64 strings in either
+// alphabet, as protojson accepts, and URN fields are strings in the URN
+// String form. Unknown members are allowed, since the facades ignore them.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/notification/v1"
+	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+	name "github.com/tinywideclouds/go-platform/pkg/user/v1"
+)
+
+// Dialect is the JSON Schema version the documents declare in "$schema".
+const Dialect = "https://json-schema.org/draft/2020-12/schema"
+
+// urnPattern matches the String form of a non-zero URN: the scheme and three
+// non-empty parts. A lookup entity ID may itself contain colons.
+var urnPattern = fmt.Sprintf(`^%s:[^:\s]+:[^:\s]+:.+$`, urn.Scheme)
+
+// base64Pattern matches standard or URL-safe base64, padded or not.
+const base64Pattern = `^[A-Za-z0-9+/_-]*={0,2}$`
+
+// UserJSONSchema returns the JSON Schema document for the JSON form of
+// name.User. The id is a URN string, or null for a zero ID.
+func UserJSONSchema() []byte {
+	return document("User", must(name.User{}.OpenAPISchema()), []string{"id"}, nil)
+}
+
+// SecureEnvelopeJSONSchema returns the JSON Schema document for the JSON form
+// of secure.SecureEnvelope. The recipient is required.
+func SecureEnvelopeJSONSchema() []byte {
+	return document("SecureEnvelope", must(secure.SecureEnvelope{}.OpenAPISchema()),
+		[]string{"recipientId"}, []string{"recipientId"})
+}
+
+// NotificationRequestJSONSchema returns the JSON Schema document for the JSON
+// form of notification.NotificationRequest. The recipient is required, so
+// the null written for a zero recipient is rejected.
+func NotificationRequestJSONSchema() []byte {
+	return document("NotificationRequest", must(notification.NotificationRequest{}.OpenAPISchema()),
+		[]string{"recipientId"}, []string{"recipientId"})
+}
+
+// must unwraps an OpenAPISchema result. The schemas are built from compiled
+// descriptors, so an error is a programming error.
+func must(schema map[string]any, err error) map[string]any {
+	if err != nil {
+		panic(fmt.Sprintf("schema: %v", err))
+	}
+	return schema
+}
+
+// document turns an OpenAPI schema object into a standalone JSON Schema
+// document: the urnFields properties become URN strings, byte fields gain
+// their encoding, and required is set. A URN field that is not required may
+// also be null, which is how the facades write a zero URN.
+func document(title string, schema map[string]any, urnFields, required []string) []byte {
+	props := schema["properties"].(map[string]any)
+	for _, field := range urnFields {
+		props[field] = urnSchema()
+		if !slices.Contains(required, field) {
+			props[field].(map[string]any)["type"] = []string{"string", "null"}
+		}
+	}
+	annotateBytes(schema)
+
+	schema["$schema"] = Dialect
+	schema["title"] = title
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		panic(fmt.Sprintf("schema: %s: %v", title, err))
+	}
+	return data
+}
+
+func urnSchema() map[string]any {
+	return map[string]any{"type": "string", "pattern": urnPattern}
+}
+
+// annotateBytes walks schema and gives every "format": "byte" string the
+// JSON Schema base64 encoding and a pattern, since validators ignore the
+// OpenAPI format.
+func annotateBytes(schema map[string]any) {
+	if schema["format"] == "byte" {
+		schema["contentEncoding"] = "base64"
+		schema["pattern"] = base64Pattern
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := schema[key].(map[string]any); ok {
+			annotateBytes(sub)
+		}
+	}
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for _, sub := range props {
+			if sub, ok := sub.(map[string]any); ok {
+				annotateBytes(sub)
+			}
+		}
+	}
+}
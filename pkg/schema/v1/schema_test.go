@@ -0,0 +1,204 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/notification/v1"
+	"github.com/tinywideclouds/go-platform/pkg/schema/v1"
+	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+	name "github.com/tinywideclouds/go-platform/pkg/user/v1"
+)
+
+// validate checks doc against the subset of JSON Schema the generated
+// documents use: type, properties, required, items, additionalProperties,
+// enum and pattern.
+func validate(schema map[string]any, doc any, path string) error {
+	if types, ok := schema["type"]; ok && !slices.ContainsFunc(typeList(types), func(t string) bool { return hasType(doc, t) }) {
+		return fmt.Errorf("%s: %v is not of type %v", path, doc, types)
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, doc) {
+		return fmt.Errorf("%s: %v is not one of %v", path, doc, enum)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if s, isString := doc.(string); isString && !regexp.MustCompile(pattern).MatchString(s) {
+			return fmt.Errorf("%s: %q does not match %s", path, s, pattern)
+		}
+	}
+	switch doc := doc.(type) {
+	case map[string]any:
+		for _, req := range asSlice(schema["required"]) {
+			if _, ok := doc[req.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %q", path, req)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for key, value := range doc {
+			sub, ok := props[key].(map[string]any)
+			if !ok {
+				sub, ok = schema["additionalProperties"].(map[string]any)
+			}
+			if ok {
+				if err := validate(sub, value, path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, value := range doc {
+				if err := validate(items, value, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func typeList(types any) []string {
+	if t, ok := types.(string); ok {
+		return []string{t}
+	}
+	var out []string
+	for _, t := range asSlice(types) {
+		out = append(out, t.(string))
+	}
+	return out
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func hasType(doc any, t string) bool {
+	switch doc := doc.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && doc == math.Trunc(doc))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+// check validates each document, given as a Go value to marshal or as raw
+// JSON, against the generated schema.
+func check(t *testing.T, schemaJSON []byte, doc any) error {
+	t.Helper()
+	var schemaDoc map[string]any
+	require.NoError(t, json.Unmarshal(schemaJSON, &schemaDoc))
+	assert.Equal(t, schema.Dialect, schemaDoc["$schema"])
+
+	raw, ok := doc.(string)
+	if !ok {
+		data, err := json.Marshal(doc)
+		require.NoError(t, err)
+		raw = string(data)
+	}
+	var decoded any
+	require.NoError(t, json.Unmarshal([]byte(raw), &decoded))
+	return validate(schemaDoc, decoded, "$")
+}
+
+func mustParse(t *testing.T, s string) urn.URN {
+	t.Helper()
+	u, err := urn.Parse(s)
+	require.NoError(t, err)
+	return u
+}
+
+func TestUserJSONSchema(t *testing.T) {
+	doc := schema.UserJSONSchema()
+
+	good := name.User{
+		ID:        mustParse(t, "urn:sm:user:testy"),
+		Alias:     "Testy",
+		Email:     "test@example.com",
+		AvatarURL: "https://example.com/a.png",
+		Status:    name.StatusActive,
+	}
+	assert.NoError(t, check(t, doc, good))
+	assert.NoError(t, check(t, doc, name.User{Alias: "no id"}))
+
+	for _, bad := range []string{
+		`{"id":"user-123"}`,
+		`{"id":""}`,
+		`{"alias":5}`,
+		`{"status":"banned"}`,
+	} {
+		assert.Error(t, check(t, doc, bad), bad)
+	}
+}
+
+func TestSecureEnvelopeJSONSchema(t *testing.T) {
+	doc := schema.SecureEnvelopeJSONSchema()
+
+	good := secure.SecureEnvelope{
+		RecipientID:           mustParse(t, "urn:lookup:ext:a:b"),
+		EncryptedData:         []byte{0xfb, 0xff, 0x01},
+		EncryptedSymmetricKey: []byte{4, 5, 6},
+		AssociatedData:        []byte("aad"),
+		ContentType:           secure.ContentTypeText,
+		Priority:              3,
+		SchemaVersion:         secure.CurrentSchemaVersion,
+	}
+	assert.NoError(t, check(t, doc, good))
+	assert.NoError(t, check(t, doc, `{"recipientId":"urn:sm:user:bob","encryptedData":"-_8B"}`), "URL-safe base64")
+
+	for _, bad := range []string{
+		`{"encryptedData":"AQID"}`,
+		`{"recipientId":null}`,
+		`{"recipientId":"urn:sm:user:bob","encryptedData":"not base64!"}`,
+		`{"recipientId":"urn:sm:user:bob","isEphemeral":"yes"}`,
+		`{"recipientId":"urn:sm:user:bob","compression":"zstd"}`,
+	} {
+		assert.Error(t, check(t, doc, bad), bad)
+	}
+}
+
+func TestNotificationRequestJSONSchema(t *testing.T) {
+	doc := schema.NotificationRequestJSONSchema()
+
+	good := &notification.NotificationRequest{
+		RecipientID: mustParse(t, "urn:sm:user:bob"),
+		FCMTokens:   []string{"token-1"},
+		WebSubscriptions: []notification.WebPushSubscription{{
+			Endpoint: "https://push.example.com/abc",
+		}},
+		Content:         notification.NotificationContent{Title: "Hi", Body: "there"},
+		DataPayload:     map[string]string{"k": "v"},
+		TargetPlatforms: []string{notification.TargetWeb},
+		ExpiresAt:       1_700_000_000_000,
+	}
+	good.WebSubscriptions[0].Keys.P256dh = []byte{4, 1, 2}
+	good.WebSubscriptions[0].Keys.Auth = []byte{9}
+	assert.NoError(t, check(t, doc, good))
+	assert.NoError(t, check(t, doc, &notification.NotificationRequest{RecipientID: good.RecipientID}), "null targets and payload")
+
+	for _, bad := range []any{
+		&notification.NotificationRequest{},
+		`{"recipientId":"urn:sm:user:bob","webSubscriptions":[{"endpoint":"https://x","p256dh":"%%%"}]}`,
+		`{"recipientId":"urn:sm:user:bob","targetPlatforms":["symbian"]}`,
+		`{"recipientId":"urn:sm:user:bob","dataPayload":{"k":1}}`,
+		`{"recipientId":"urn:sm:user:bob","content":{"title":true}}`,
+	} {
+		assert.Error(t, check(t, doc, bad), "%v", bad)
+	}
+}
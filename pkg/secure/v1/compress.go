@@ -0,0 +1,44 @@
+package secure
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// SecureEnvelope.Compression values. Compression is applied by the sender to
+// the plaintext before encryption; the facade never touches EncryptedData.
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+)
+
+// CompressPayload gzips plaintext ahead of encryption. Record
+// CompressionGzip in the envelope's Compression field so the recipient knows
+// to call DecompressPayload after decrypting.
+func CompressPayload(plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecompressPayload reverses CompressPayload on a decrypted payload.
+func DecompressPayload(compressed []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	defer zr.Close()
+	plaintext, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	return plaintext, nil
+}
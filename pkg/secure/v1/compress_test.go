@@ -0,0 +1,68 @@
+package secure_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+)
+
+func TestCompressPayload_RoundTrip(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("hello, compressible world. "), 200)
+
+	t.Run("gzip", func(t *testing.T) {
+		compressed, err := secure.CompressPayload(plaintext)
+		require.NoError(t, err)
+		assert.Less(t, len(compressed), len(plaintext))
+
+		got, err := secure.DecompressPayload(compressed)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	})
+
+	t.Run("gzip empty", func(t *testing.T) {
+		compressed, err := secure.CompressPayload(nil)
+		require.NoError(t, err)
+		got, err := secure.DecompressPayload(compressed)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Not gzip", func(t *testing.T) {
+		_, err := secure.DecompressPayload(plaintext)
+		assert.Error(t, err)
+	})
+}
+
+func TestSecureEnvelope_Compression(t *testing.T) {
+	for _, compression := range []string{secure.CompressionNone, secure.CompressionGzip} {
+		t.Run("Round trip "+compression, func(t *testing.T) {
+			env := newTestEnvelope(t)
+			env.Compression = compression
+			original := append([]byte(nil), env.EncryptedData...)
+			require.NoError(t, env.Validate())
+
+			data, err := json.Marshal(env)
+			require.NoError(t, err)
+			var got secure.SecureEnvelope
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, *env, got)
+			assert.Equal(t, original, got.EncryptedData, "ciphertext must be left untouched")
+		})
+	}
+
+	t.Run("Unknown algorithm", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		env.Compression = "brotli"
+		err := env.Validate()
+		require.ErrorIs(t, err, secure.ErrInvalidEnvelope)
+		fieldErr, ok := validation.AsFieldError(err)
+		require.True(t, ok)
+		assert.Equal(t, "compression", fieldErr.Field())
+	})
+}
@@ -7,32 +7,64 @@ corresponding bug in 'ToProto' (which passed the wrong type) are both fixed.
 package secure
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
 
 	// --- NEW IMPORTS ---
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	// ---
 	smv1 "github.com/tinywideclouds/gen-platform/go/types/secure/v1"
+	"github.com/tinywideclouds/go-platform/internal/cborpb"
+	"github.com/tinywideclouds/go-platform/internal/convert"
+	"github.com/tinywideclouds/go-platform/internal/jsonext"
+	"github.com/tinywideclouds/go-platform/internal/msgpackpb"
+	"github.com/tinywideclouds/go-platform/internal/openapi"
+	"github.com/tinywideclouds/go-platform/internal/yamljson"
+	"github.com/tinywideclouds/go-platform/pkg/facade/v1"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+	"gopkg.in/yaml.v3"
 )
 
-// --- Marshal/Unmarshal Options ---
-var (
-	// protojsonMarshalOptions tells protojson to use camelCase (json_name)
-	protojsonMarshalOptions = &protojson.MarshalOptions{
-		UseProtoNames:   false, // Use camelCase
-		EmitUnpopulated: false,
-	}
+type SecureEnvelopePb = smv1.SecureEnvelopePb
+type SecureEnvelopeListPb = smv1.SecureEnvelopeListPb
 
-	// protojsonUnmarshalOptions tells protojson to ignore unknown fields.
-	protojsonUnmarshalOptions = &protojson.UnmarshalOptions{
-		DiscardUnknown: true,
-	}
+var (
+	// ErrInvalidEnvelope is wrapped by the errors Validate returns.
+	ErrInvalidEnvelope = errors.New("invalid secure envelope")
+	// ErrUnsupportedSchemaVersion is wrapped by RequireVersion.
+	ErrUnsupportedSchemaVersion = errors.New("unsupported envelope schema version")
+	// ErrInvalidCursor is wrapped by Page for a cursor it did not issue.
+	ErrInvalidCursor = errors.New("invalid page cursor")
 )
 
-type SecureEnvelopePb = smv1.SecureEnvelopePb
-type SecureEnvelopeListPb = smv1.SecureEnvelopeListPb
+// CurrentSchemaVersion is the SecureEnvelope.SchemaVersion this package
+// produces. FromProto and the decoders assume it when an envelope carries no
+// version, since those predate versioning.
+const CurrentSchemaVersion int32 = 1
+
+// Common SecureEnvelope.ContentType values. Any MIME type without control
+// characters is accepted; these are the ones our clients send.
+const (
+	// ContentTypeUnknown is the empty content type: the sender gave no hint.
+	ContentTypeUnknown = ""
+	ContentTypeText    = "text/plain"
+	ContentTypeJSON    = "application/json"
+	ContentTypeJPEG    = "image/jpeg"
+	ContentTypePNG     = "image/png"
+	// ContentTypeControl marks a control message (receipts, typing
+	// indicators, key rotation) rather than user content.
+	ContentTypeControl = "application/vnd.tinywide.control+json"
+)
 
 // --- SecureEnvelope (Single) ---
 
@@ -45,6 +77,60 @@ type SecureEnvelope struct {
 	Signature             []byte  `json:"signature,omitempty"`
 	IsEphemeral           bool    `json:"isEphemeral,omitempty"`
 	Priority              int32   `json:"priority,omitempty"`
+
+	// The fields below are not part of SecureEnvelopePb yet: ToProto drops
+	// them, and the JSON, msgpack and CBOR forms carry them alongside the
+	// proto fields.
+
+	// AssociatedData is the AEAD additional authenticated data the sender
+	// bound to the ciphertext (e.g. the recipient URN and a content type), so
+	// the receiver can rebuild it to decrypt. It is authenticated but not
+	// encrypted: anyone holding the envelope can read it.
+	AssociatedData []byte `json:"associatedData,omitempty"`
+
+	// ContentType is the MIME type of the decrypted payload, e.g.
+	// ContentTypeText. Empty means unknown.
+	ContentType string `json:"contentType,omitempty"`
+
+	// Compression names the algorithm the plaintext was compressed with
+	// before encryption (CompressionNone or CompressionGzip). See
+	// CompressPayload.
+	Compression string `json:"compression,omitempty"`
+
+	// SchemaVersion tells the receiver which field semantics apply. Zero is
+	// never produced by the decoders; see CurrentSchemaVersion.
+	SchemaVersion int32 `json:"schemaVersion,omitempty"`
+
+	// unknown holds the JSON members UnmarshalJSONPreserveUnknown did not
+	// recognize, for MarshalJSON to write back out.
+	unknown map[string]json.RawMessage
+}
+
+// envelopeExt holds the SecureEnvelope fields that SecureEnvelopePb cannot
+// carry.
+type envelopeExt struct {
+	AssociatedData []byte `json:"associatedData,omitempty" msgpack:"associatedData,omitempty"`
+	ContentType    string `json:"contentType,omitempty" msgpack:"contentType,omitempty"`
+	Compression    string `json:"compression,omitempty" msgpack:"compression,omitempty"`
+	SchemaVersion  int32  `json:"schemaVersion,omitempty" msgpack:"schemaVersion,omitempty"`
+}
+
+func (se *SecureEnvelope) ext() envelopeExt {
+	return envelopeExt{
+		AssociatedData: se.AssociatedData,
+		ContentType:    se.ContentType,
+		Compression:    se.Compression,
+		SchemaVersion:  se.SchemaVersion,
+	}
+}
+
+func (se *SecureEnvelope) setExt(ext envelopeExt) {
+	se.AssociatedData = ext.AssociatedData
+	se.ContentType = ext.ContentType
+	se.Compression = ext.Compression
+	if ext.SchemaVersion != 0 {
+		se.SchemaVersion = ext.SchemaVersion
+	}
 }
 
 // ToProto converts the idiomatic Go struct into its Protobuf representation.
@@ -66,7 +152,17 @@ func ToProto(native *SecureEnvelope) *SecureEnvelopePb {
 	}
 }
 
+// ToProtoMessage implements facade.Protoer, returning ToProto(&se) as a
+// *SecureEnvelopePb.
+func (se SecureEnvelope) ToProtoMessage() proto.Message {
+	return ToProto(&se)
+}
+
+var _ facade.Protoer = SecureEnvelope{}
+
 // FromProto converts the Protobuf representation into the idiomatic Go struct.
+// SecureEnvelopePb has no version field, so SchemaVersion is set to
+// CurrentSchemaVersion.
 func FromProto(native *SecureEnvelopePb) (*SecureEnvelope, error) {
 	if native == nil {
 		return nil, nil
@@ -76,7 +172,7 @@ func FromProto(native *SecureEnvelopePb) (*SecureEnvelope, error) {
 	// 2. We MUST check the error it returns. This is what the test caught.
 	recipient, err := urn.Parse(native.GetRecipientId())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse recipient URN from proto: %w", err)
+		return nil, validation.NewFieldError("recipientId", "failed to parse recipient URN from proto", err)
 	}
 
 	return &SecureEnvelope{
@@ -86,38 +182,294 @@ func FromProto(native *SecureEnvelopePb) (*SecureEnvelope, error) {
 		Signature:             native.GetSignature(),
 		IsEphemeral:           native.GetIsEphemeral(),
 		Priority:              native.GetPriority(),
+		SchemaVersion:         CurrentSchemaVersion,
 	}, nil
 }
 
+// --- Pooled proto (JSON hot path) ---
+
+// pooledEnvelope is the intermediate SecureEnvelopePb used by MarshalJSON and
+// UnmarshalJSON, with room for the Priority value so filling it does not
+// allocate either.
+type pooledEnvelope struct {
+	pb       SecureEnvelopePb
+	priority int32
+}
+
+var envelopePool = sync.Pool{
+	New: func() any { return new(pooledEnvelope) },
+}
+
+func getPooledEnvelope() *pooledEnvelope {
+	return envelopePool.Get().(*pooledEnvelope)
+}
+
+// putPooledEnvelope clears p before returning it, so no envelope data is
+// held by, or visible through, the pool.
+func putPooledEnvelope(p *pooledEnvelope) {
+	p.pb.Reset()
+	p.priority = 0
+	envelopePool.Put(p)
+}
+
+// fill is ToProto into the pooled message.
+func (p *pooledEnvelope) fill(native *SecureEnvelope) {
+	p.priority = native.Priority
+	p.pb.RecipientId = native.RecipientID.String()
+	p.pb.EncryptedData = native.EncryptedData
+	p.pb.EncryptedSymmetricKey = native.EncryptedSymmetricKey
+	p.pb.Signature = native.Signature
+	p.pb.IsEphemeral = native.IsEphemeral
+	p.pb.Priority = &p.priority
+}
+
 // --- JSON METHODS (Single) ---
 
 // MarshalJSON implements the json.Marshaler interface.
 //
 // REFACTOR: This now has a VALUE RECEIVER (no *).
 func (se SecureEnvelope) MarshalJSON() ([]byte, error) {
-	protoPb := ToProto(&se)
-	return protojsonMarshalOptions.Marshal(protoPb)
+	return se.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options, e.g.
+// UseProtoNames and EmitUnpopulated for a debug endpoint. The options apply
+// to the fields SecureEnvelopePb lacks as well; preserved unknown members are
+// written as they were read.
+func (se SecureEnvelope) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	p := getPooledEnvelope()
+	defer putPooledEnvelope(p)
+	p.fill(&se)
+	data, err := opts.Marshal(&p.pb)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = jsonext.MergeWith(data, se.ext(), opts); err != nil {
+		return nil, err
+	}
+	if len(se.unknown) == 0 {
+		return data, nil
+	}
+	return jsonext.Merge(data, se.unknown)
+}
+
+// MarshalCanonical returns the JSON form with sorted keys and no
+// insignificant whitespace, so equal values always give identical bytes.
+// Use it for hashing and signing rather than MarshalJSON.
+func (se SecureEnvelope) MarshalCanonical() ([]byte, error) {
+	data, err := se.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jsonext.Canonical(data)
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 // This remains a POINTER RECEIVER (*se) to modify the struct.
+//
+// It is all-or-nothing: *se is assigned only once the whole input has been
+// decoded and converted, so on error se is left exactly as it was. It is
+// safe on arbitrary client input (see FuzzSecureEnvelopeUnmarshal).
 func (se *SecureEnvelope) UnmarshalJSON(data []byte) error {
+	p := getPooledEnvelope()
+	defer putPooledEnvelope(p)
+	if err := convert.UnmarshalOptions().Unmarshal(data, &p.pb); err != nil {
+		return err
+	}
+	// FromProto takes the byte slices protojson just allocated; Reset in
+	// putPooledEnvelope only drops the pool's references to them.
+	native, err := FromProto(&p.pb)
+	if err != nil {
+		return err
+	}
+	var ext envelopeExt
+	if err := jsonext.Unmarshal(data, &ext); err != nil {
+		return err
+	}
+	native.setExt(ext)
+	*se = *native
+	return nil
+}
+
+// envelopeKnownMembers are the JSON members UnmarshalJSON reads.
+var envelopeKnownMembers = jsonext.KnownMembers((&SecureEnvelopePb{}).ProtoReflect().Descriptor(), envelopeExt{})
+
+// UnmarshalJSONPreserveUnknown is UnmarshalJSON for services that forward
+// envelopes between mixed versions. UnmarshalJSON drops members it does not
+// recognize; this keeps them, values as written, and MarshalJSON writes them
+// back, so a field added by a newer producer survives a re-serialization by
+// an older service. Only the JSON form carries them: ToProto, msgpack and
+// CBOR still drop them. Like UnmarshalJSON, it is all-or-nothing.
+func (se *SecureEnvelope) UnmarshalJSONPreserveUnknown(data []byte) error {
+	var native SecureEnvelope
+	if err := native.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	unknown, err := jsonext.Unknown(data, envelopeKnownMembers)
+	if err != nil {
+		return err
+	}
+	native.unknown = unknown
+	*se = native
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface using the JSON form.
+func (se SecureEnvelope) MarshalYAML() (any, error) {
+	return yamljson.Marshal(se)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface using the JSON form.
+func (se *SecureEnvelope) UnmarshalYAML(node *yaml.Node) error {
+	return yamljson.Unmarshal(node, se)
+}
+
+// --- Msgpack Methods ---
+
+// MarshalMsgpack implements the msgpack.Marshaler interface. The
+// SecureEnvelopePb is encoded as a map keyed like the JSON form, with the byte
+// fields as raw msgpack bin values.
+func (se SecureEnvelope) MarshalMsgpack() ([]byte, error) {
+	return msgpackpb.MarshalExt(ToProto(&se), se.ext())
+}
+
+// UnmarshalMsgpack implements the msgpack.Unmarshaler interface.
+func (se *SecureEnvelope) UnmarshalMsgpack(data []byte) error {
 	var protoPb SecureEnvelopePb
-	if err := protojsonUnmarshalOptions.Unmarshal(data, &protoPb); err != nil {
+	var ext envelopeExt
+	if err := msgpackpb.UnmarshalExt(data, &protoPb, &ext); err != nil {
 		return err
 	}
 	native, err := FromProto(&protoPb)
 	if err != nil {
 		return err
 	}
-	if native != nil {
-		*se = *native
-	} else {
+	native.setExt(ext)
+	*se = *native
+	return nil
+}
+
+// --- CBOR Methods ---
+
+// MarshalCBOR implements the cbor.Marshaler interface. The envelope is a CBOR
+// map keyed like the JSON form, with the byte fields as CBOR byte strings,
+// in deterministic encoding.
+func (se SecureEnvelope) MarshalCBOR() ([]byte, error) {
+	return cborpb.MarshalMessageExt(ToProto(&se), se.ext())
+}
+
+// UnmarshalCBOR implements the cbor.Unmarshaler interface. CBOR null leaves
+// the envelope empty.
+func (se *SecureEnvelope) UnmarshalCBOR(data []byte) error {
+	if cborpb.IsNull(data) {
 		*se = SecureEnvelope{}
+		return nil
 	}
+	var protoPb SecureEnvelopePb
+	var ext envelopeExt
+	if err := cborpb.UnmarshalMessageExt(data, &protoPb, &ext); err != nil {
+		return err
+	}
+	native, err := FromProto(&protoPb)
+	if err != nil {
+		return err
+	}
+	native.setExt(ext)
+	*se = *native
 	return nil
 }
 
+// UnmarshalLenientJSON is a forgiving variant of UnmarshalJSON for
+// compatibility endpoints. It accepts the standard protojson form and, failing
+// that, a loose form where the byte fields are base64 strings in either
+// alphabet, with or without padding, and possibly broken up by whitespace.
+// The strict UnmarshalJSON is unaffected.
+func (se *SecureEnvelope) UnmarshalLenientJSON(data []byte) error {
+	var strict SecureEnvelope
+	if err := strict.UnmarshalJSON(data); err == nil {
+		*se = strict
+		return nil
+	}
+
+	var loose struct {
+		RecipientID           string `json:"recipientId"`
+		EncryptedData         string `json:"encryptedData"`
+		EncryptedSymmetricKey string `json:"encryptedSymmetricKey"`
+		Signature             string `json:"signature"`
+		IsEphemeral           bool   `json:"isEphemeral"`
+		Priority              int32  `json:"priority"`
+		AssociatedData        string `json:"associatedData"`
+		ContentType           string `json:"contentType"`
+		Compression           string `json:"compression"`
+		SchemaVersion         int32  `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &loose); err != nil {
+		return err
+	}
+
+	recipient, err := urn.Parse(loose.RecipientID)
+	if err != nil {
+		return fmt.Errorf("failed to parse recipient URN: %w", err)
+	}
+	encryptedData, err := decodeLooseBase64("encryptedData", loose.EncryptedData)
+	if err != nil {
+		return err
+	}
+	encryptedSymmetricKey, err := decodeLooseBase64("encryptedSymmetricKey", loose.EncryptedSymmetricKey)
+	if err != nil {
+		return err
+	}
+	signature, err := decodeLooseBase64("signature", loose.Signature)
+	if err != nil {
+		return err
+	}
+	associatedData, err := decodeLooseBase64("associatedData", loose.AssociatedData)
+	if err != nil {
+		return err
+	}
+
+	*se = SecureEnvelope{
+		RecipientID:           recipient,
+		EncryptedData:         encryptedData,
+		EncryptedSymmetricKey: encryptedSymmetricKey,
+		Signature:             signature,
+		IsEphemeral:           loose.IsEphemeral,
+		Priority:              loose.Priority,
+		AssociatedData:        associatedData,
+		ContentType:           loose.ContentType,
+		Compression:           loose.Compression,
+		SchemaVersion:         loose.SchemaVersion,
+	}
+	if se.SchemaVersion == 0 {
+		se.SchemaVersion = CurrentSchemaVersion
+	}
+	return nil
+}
+
+// decodeLooseBase64 decodes standard or URL-safe base64, ignoring padding and
+// whitespace. An empty string decodes to nil, matching protojson.
+func decodeLooseBase64(field, s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r), r == '=':
+			return -1
+		case r == '-':
+			return '+'
+		case r == '_':
+			return '/'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 in %s: %w", field, err)
+	}
+	return b, nil
+}
+
 // --- SecureEnvelopeList (List) ---
 
 // SecureEnvelopeList is the idiomatic Go struct for a list of envelopes.
@@ -130,28 +482,29 @@ func ListToProto(native *SecureEnvelopeList) *SecureEnvelopeListPb {
 	if native == nil {
 		return nil
 	}
-	protoEnvelopes := make([]*SecureEnvelopePb, len(native.Envelopes))
-	for i, env := range native.Envelopes {
-		protoEnvelopes[i] = ToProto(env)
-	}
 	return &SecureEnvelopeListPb{
-		Envelopes: protoEnvelopes,
+		Envelopes: convert.List(native.Envelopes, ToProto),
 	}
 }
 
 // ListFromProto converts the Protobuf list into the idiomatic Go struct.
 func ListFromProto(proto *SecureEnvelopeListPb) (*SecureEnvelopeList, error) {
+	return ListFromProtoContext(context.Background(), proto)
+}
+
+// ListFromProtoContext is ListFromProto for large lists: it checks ctx
+// periodically while converting and returns ctx.Err() once it is done.
+func ListFromProtoContext(ctx context.Context, proto *SecureEnvelopeListPb) (*SecureEnvelopeList, error) {
 	if proto == nil {
 		return nil, nil
 	}
-	nativeEnvelopes := make([]*SecureEnvelope, len(proto.Envelopes))
-	var err error
-	for i, pEnv := range proto.Envelopes {
-		nativeEnvelopes[i], err = FromProto(pEnv)
-		if err != nil {
-			// Wrap the error with context about which envelope failed
-			return nil, fmt.Errorf("failed to parse envelope at index %d: %w", i, err)
+	nativeEnvelopes, err := convert.ListErrContext(ctx, proto.Envelopes, FromProto)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
 		}
+		// Wrap the error with context about which envelope failed
+		return nil, fmt.Errorf("failed to parse envelope %w", err)
 	}
 	return &SecureEnvelopeList{
 		Envelopes: nativeEnvelopes,
@@ -160,29 +513,227 @@ func ListFromProto(proto *SecureEnvelopeListPb) (*SecureEnvelopeList, error) {
 
 // --- JSON METHODS (List) ---
 
+// The list's JSON is built from each envelope's own JSON rather than from
+// SecureEnvelopeListPb, so the fields the proto does not carry survive.
+
+// secureEnvelopeListJSON is the wire form of SecureEnvelopeList.
+type secureEnvelopeListJSON struct {
+	Envelopes []json.RawMessage `json:"envelopes,omitempty"`
+}
+
 // MarshalJSON implements the json.Marshaler interface for SecureEnvelopeList.
 //
 // REFACTOR: This now has a VALUE RECEIVER (no *).
 func (sel SecureEnvelopeList) MarshalJSON() ([]byte, error) {
-	protoPb := ListToProto(&sel)
-	return protojsonMarshalOptions.Marshal(protoPb)
+	return sel.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options,
+// applied to every envelope.
+func (sel SecureEnvelopeList) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	return jsonext.MarshalList("envelopes", sel.Envelopes, opts)
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for SecureEnvelopeList.
 // This remains a POINTER RECEIVER (*sel) to modify the struct.
 func (sel *SecureEnvelopeList) UnmarshalJSON(data []byte) error {
-	var protoPb SecureEnvelopeListPb
-	if err := protojsonUnmarshalOptions.Unmarshal(data, &protoPb); err != nil {
+	var wire secureEnvelopeListJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
 		return err
 	}
-	native, err := ListFromProto(&protoPb)
+	native := SecureEnvelopeList{}
+	if len(wire.Envelopes) > 0 {
+		native.Envelopes = make([]*SecureEnvelope, len(wire.Envelopes))
+	}
+	for i, raw := range wire.Envelopes {
+		if string(raw) == "null" {
+			continue
+		}
+		env := &SecureEnvelope{}
+		if err := env.UnmarshalJSON(raw); err != nil {
+			return fmt.Errorf("failed to parse envelope at index %d: %w", i, err)
+		}
+		native.Envelopes[i] = env
+	}
+	*sel = native
+	return nil
+}
+
+// --- Pagination ---
+
+// Page returns up to limit envelopes starting at cursor, plus the cursor for
+// the following page. An empty cursor starts at the beginning, and
+// nextCursor is empty once the list is exhausted. Cursors are opaque to
+// clients; an unparseable or out-of-range one wraps ErrInvalidCursor. The
+// page shares its envelopes with sel.
+func (sel *SecureEnvelopeList) Page(cursor string, limit int) (page *SecureEnvelopeList, nextCursor string, err error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("page limit must be positive, got %d", limit)
+	}
+	start, err := decodeCursor(cursor)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+	total := len(sel.Envelopes)
+	if start > total {
+		return nil, "", fmt.Errorf("%w: offset %d is past the end of the list", ErrInvalidCursor, start)
+	}
+
+	end := min(start+limit, total)
+	page = &SecureEnvelopeList{Envelopes: sel.Envelopes[start:end:end]}
+	if end < total {
+		nextCursor = encodeCursor(end)
+	}
+	return page, nextCursor, nil
+}
+
+// SplitBatches splits sel, in order, into batches of at most maxCount
+// envelopes whose EstimatedSize sum to at most maxBytes. A limit of zero or
+// less is not applied. An envelope larger than maxBytes on its own goes in a
+// batch by itself. Since EstimatedSize leaves out the wire overhead, set
+// maxBytes below the downstream limit. The batches share their envelopes
+// with sel; an empty list gives no batches.
+func (sel *SecureEnvelopeList) SplitBatches(maxCount int, maxBytes int) []*SecureEnvelopeList {
+	var batches []*SecureEnvelopeList
+	start, size := 0, 0
+	for i, env := range sel.Envelopes {
+		envSize := 0
+		if env != nil {
+			envSize = env.EstimatedSize()
+		}
+		full := maxCount > 0 && i-start == maxCount
+		tooBig := maxBytes > 0 && size+envSize > maxBytes
+		if i > start && (full || tooBig) {
+			batches = append(batches, &SecureEnvelopeList{Envelopes: sel.Envelopes[start:i:i]})
+			start, size = i, 0
+		}
+		size += envSize
+	}
+	if end := len(sel.Envelopes); end > start {
+		batches = append(batches, &SecureEnvelopeList{Envelopes: sel.Envelopes[start:end:end]})
+	}
+	return batches
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return offset, nil
+}
+
+// --- Relaying ---
+
+// WithRecipient returns a shallow copy of se addressed to r, for a relay
+// re-addressing an envelope without touching the original. The payloads are
+// not deep-copied: the copy shares EncryptedData and the other byte slices
+// with se, so neither should be modified in place. The recipient is a signed
+// field, so the copy no longer verifies against se's Signature.
+func (se *SecureEnvelope) WithRecipient(r urn.URN) *SecureEnvelope {
+	relayed := *se
+	relayed.RecipientID = r
+	return &relayed
+}
+
+// --- Sizing ---
+
+// EstimatedSize returns the envelope's payload size in bytes without
+// serializing it: the lengths of the byte fields plus the recipient URN and
+// the content type and compression names. It is approximate. Field tags,
+// fixed-size fields and the JSON overhead (quoting, member names, and base64
+// growing the byte fields by a third) are not counted, so the JSON, msgpack
+// and CBOR forms are somewhat larger; allow for that when comparing against
+// a byte budget.
+func (se *SecureEnvelope) EstimatedSize() int {
+	return len(se.RecipientID.String()) +
+		len(se.EncryptedData) +
+		len(se.EncryptedSymmetricKey) +
+		len(se.Signature) +
+		len(se.AssociatedData) +
+		len(se.ContentType) +
+		len(se.Compression)
+}
+
+// --- Logging ---
+
+// LogValue implements slog.LogValuer with the envelope's metadata only: the
+// byte fields are logged by length, never by content, so ciphertext, wrapped
+// keys and associated data stay out of the logs.
+func (se SecureEnvelope) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("recipientId", se.RecipientID.String()),
+		slog.Int("encryptedDataLen", len(se.EncryptedData)),
+		slog.Int("encryptedSymmetricKeyLen", len(se.EncryptedSymmetricKey)),
+		slog.Bool("signed", len(se.Signature) > 0),
+		slog.Bool("isEphemeral", se.IsEphemeral),
+		slog.Int("priority", int(se.Priority)),
+		slog.Int("schemaVersion", int(se.SchemaVersion)),
+	}
+	if len(se.AssociatedData) > 0 {
+		attrs = append(attrs, slog.Int("associatedDataLen", len(se.AssociatedData)))
 	}
-	if native != nil {
-		*sel = *native
-	} else {
-		*sel = SecureEnvelopeList{}
+	if se.ContentType != "" {
+		attrs = append(attrs, slog.String("contentType", se.ContentType))
+	}
+	if se.Compression != "" {
+		attrs = append(attrs, slog.String("compression", se.Compression))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// --- Schema ---
+
+// OpenAPISchema returns the OpenAPI 3.1 schema object for the JSON form of
+// SecureEnvelope, derived from the proto descriptor.
+func (se SecureEnvelope) OpenAPISchema() (map[string]any, error) {
+	schema := openapi.Schema((&SecureEnvelopePb{}).ProtoReflect().Descriptor())
+	props := openapi.Properties(schema)
+	props["associatedData"] = map[string]any{"type": "string", "format": "byte"}
+	props["contentType"] = map[string]any{"type": "string"}
+	props["schemaVersion"] = map[string]any{"type": "integer", "format": "int32"}
+	props["compression"] = map[string]any{"type": "string", "enum": []any{CompressionNone, CompressionGzip}}
+	return schema, nil
+}
+
+// --- Validation ---
+
+// Validate checks the envelope's structure: RecipientID must be set,
+// ContentType, if present, must not contain control characters, and
+// Compression must be a known algorithm. Errors are *validation.FieldError
+// values wrapping ErrInvalidEnvelope.
+func (se SecureEnvelope) Validate() error {
+	if se.RecipientID.IsZero() {
+		return validation.NewFieldError("recipientId", "is required", ErrInvalidEnvelope)
+	}
+	if strings.IndexFunc(se.ContentType, unicode.IsControl) >= 0 {
+		return validation.NewFieldError("contentType", "contains control characters", ErrInvalidEnvelope)
+	}
+	switch se.Compression {
+	case CompressionNone, CompressionGzip:
+	default:
+		return validation.NewFieldError("compression", fmt.Sprintf("unsupported algorithm %q", se.Compression), ErrInvalidEnvelope)
+	}
+	return nil
+}
+
+// RequireVersion returns an error wrapping ErrUnsupportedSchemaVersion if
+// the envelope's SchemaVersion is below min, for receivers that depend on
+// semantics introduced in a later version.
+func (se *SecureEnvelope) RequireVersion(min int32) error {
+	if se.SchemaVersion < min {
+		return fmt.Errorf("%w: envelope is version %d, need at least %d", ErrUnsupportedSchemaVersion, se.SchemaVersion, min)
 	}
 	return nil
 }
@@ -6,15 +6,24 @@ to validate the ToProto/FromProto facade functions.
 package secure_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/json" // We use the standard 'json' lib to test the interface
+	"log/slog"
+	"sync"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
 
 	// --- Import the native packages we are testing ---
 	"github.com/tinywideclouds/go-platform/pkg/net/v1"
 	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
 )
 
 // Helper to create a valid native SecureEnvelope for tests
@@ -29,6 +38,7 @@ func newTestEnvelope(t *testing.T) *secure.SecureEnvelope {
 		EncryptedData:         []byte{1, 2, 3},
 		EncryptedSymmetricKey: []byte{4, 5, 6},
 		Signature:             []byte{7, 8, 9},
+		SchemaVersion:         secure.CurrentSchemaVersion,
 	}
 }
 
@@ -77,6 +87,7 @@ func TestSecureEnvelope_JSON_RoundTrip(t *testing.T) {
 		"encryptedData": "AQID",
 		"encryptedSymmetricKey": "BAUG",
 		"priority": 0,
+		"schemaVersion": 1,
 		"signature": "BwgJ"
 	}`
 
@@ -136,6 +147,7 @@ func TestSecureEnvelopeList_JSON_RoundTrip(t *testing.T) {
 				EncryptedData:         []byte{1, 2, 3},
 				EncryptedSymmetricKey: []byte{4, 5, 6},
 				Signature:             []byte{7, 8, 9},
+				SchemaVersion:         secure.CurrentSchemaVersion,
 			},
 		},
 	}
@@ -148,6 +160,7 @@ func TestSecureEnvelopeList_JSON_RoundTrip(t *testing.T) {
 				"encryptedData": "AQID",
 				"priority": 0,
 				"encryptedSymmetricKey": "BAUG",
+				"schemaVersion": 1,
 				"signature": "BwgJ"
 			}
 		]
@@ -173,4 +186,808 @@ func TestSecureEnvelopeList_JSON_RoundTrip(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, nativeList, &resultList)
 	})
+
+	t.Run("Nil and empty", func(t *testing.T) {
+		withNil := secure.SecureEnvelopeList{Envelopes: []*secure.SecureEnvelope{nil}}
+		jsonBytes, err := json.Marshal(withNil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"envelopes":[null]}`, string(jsonBytes))
+
+		var resultList secure.SecureEnvelopeList
+		require.NoError(t, json.Unmarshal(jsonBytes, &resultList))
+		assert.Equal(t, withNil, resultList)
+
+		jsonBytes, err = json.Marshal(secure.SecureEnvelopeList{})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{}`, string(jsonBytes))
+	})
+}
+
+func TestSecureEnvelope_UnmarshalLenientJSON(t *testing.T) {
+	expected := newTestEnvelope(t)
+
+	t.Run("Strict protojson form", func(t *testing.T) {
+		strictJSON := `{
+			"recipientId": "urn:contacts:user:recipient-bob",
+			"encryptedData": "AQID",
+			"encryptedSymmetricKey": "BAUG",
+			"signature": "BwgJ"
+		}`
+
+		var result secure.SecureEnvelope
+		require.NoError(t, result.UnmarshalLenientJSON([]byte(strictJSON)))
+		assert.Equal(t, expected, &result)
+	})
+
+	t.Run("Loose base64 form", func(t *testing.T) {
+		// Whitespace and stray padding are rejected by protojson.
+		looseJSON := `{
+			"recipientId": "urn:contacts:user:recipient-bob",
+			"encryptedData": "AQ ID",
+			"encryptedSymmetricKey": "BAUG==",
+			"signature": "Bw\ngJ"
+		}`
+
+		var strict secure.SecureEnvelope
+		require.Error(t, json.Unmarshal([]byte(looseJSON), &strict))
+
+		var result secure.SecureEnvelope
+		require.NoError(t, result.UnmarshalLenientJSON([]byte(looseJSON)))
+		assert.Equal(t, expected, &result)
+	})
+
+	t.Run("Invalid base64", func(t *testing.T) {
+		var result secure.SecureEnvelope
+		err := result.UnmarshalLenientJSON([]byte(`{"encryptedData": "not base64!"}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "encryptedData")
+	})
+}
+
+func TestSecureEnvelope_OpenAPISchema(t *testing.T) {
+	schema, err := secure.SecureEnvelope{}.OpenAPISchema()
+	require.NoError(t, err)
+
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string"}, props["recipientId"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "byte"}, props["encryptedData"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "byte"}, props["signature"])
+}
+
+func TestSecureEnvelope_MarshalJSONWith(t *testing.T) {
+	env := newTestEnvelope(t)
+
+	decode := func(t *testing.T, data []byte) map[string]any {
+		t.Helper()
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(data, &m))
+		return m
+	}
+
+	t.Run("Default options match MarshalJSON", func(t *testing.T) {
+		want, err := json.Marshal(env)
+		require.NoError(t, err)
+		got, err := env.MarshalJSONWith(protojson.MarshalOptions{})
+		require.NoError(t, err)
+		assert.JSONEq(t, string(want), string(got))
+	})
+
+	t.Run("Proto names", func(t *testing.T) {
+		data, err := env.MarshalJSONWith(protojson.MarshalOptions{UseProtoNames: true})
+		require.NoError(t, err)
+		m := decode(t, data)
+		assert.Contains(t, m, "recipient_id")
+		assert.Contains(t, m, "encrypted_symmetric_key")
+		assert.NotContains(t, m, "recipientId")
+		assert.Equal(t, float64(secure.CurrentSchemaVersion), m["schema_version"], "extension fields are renamed too")
+		assert.NotContains(t, m, "schemaVersion")
+
+		var back secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal(data, &back))
+		assert.Equal(t, env, &back)
+	})
+
+	t.Run("Emit unpopulated", func(t *testing.T) {
+		data, err := env.MarshalJSONWith(protojson.MarshalOptions{})
+		require.NoError(t, err)
+		assert.NotContains(t, decode(t, data), "isEphemeral")
+
+		data, err = env.MarshalJSONWith(protojson.MarshalOptions{EmitUnpopulated: true})
+		require.NoError(t, err)
+		m := decode(t, data)
+		assert.Equal(t, false, m["isEphemeral"])
+		assert.Equal(t, "", m["associatedData"], "empty extension fields are emitted too")
+		assert.Equal(t, "", m["contentType"])
+		assert.Equal(t, "", m["compression"])
+	})
+
+	t.Run("List", func(t *testing.T) {
+		list := secure.SecureEnvelopeList{Envelopes: []*secure.SecureEnvelope{env}}
+		data, err := list.MarshalJSONWith(protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true})
+		require.NoError(t, err)
+		var m map[string][]map[string]any
+		require.NoError(t, json.Unmarshal(data, &m))
+		require.Len(t, m["envelopes"], 1)
+		assert.Contains(t, m["envelopes"][0], "recipient_id")
+		assert.Contains(t, m["envelopes"][0], "is_ephemeral")
+		assert.Contains(t, m["envelopes"][0], "content_type")
+	})
+}
+
+func TestSecureEnvelope_YAML_RoundTrip(t *testing.T) {
+	original := newTestEnvelope(t)
+	original.Priority = 5
+
+	out, err := yaml.Marshal(original)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "recipientId: urn:contacts:user:recipient-bob\n")
+	assert.Contains(t, string(out), "priority: 5\n")
+
+	var got secure.SecureEnvelope
+	require.NoError(t, yaml.Unmarshal(out, &got))
+	assert.Equal(t, *original, got)
+
+	t.Run("Empty envelope", func(t *testing.T) {
+		var fixture struct {
+			Envelope secure.SecureEnvelope `yaml:"envelope"`
+		}
+		require.NoError(t, yaml.Unmarshal([]byte("envelope: null\n"), &fixture))
+		assert.Equal(t, secure.SecureEnvelope{}, fixture.Envelope)
+	})
+}
+
+// cancelAfter is a context that reports context.Canceled after n checks.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestSecureEnvelopeList_FromProtoContext(t *testing.T) {
+	nativeList := &secure.SecureEnvelopeList{}
+	for range 500 {
+		nativeList.Envelopes = append(nativeList.Envelopes, newTestEnvelope(t))
+	}
+	protoListPb := secure.ListToProto(nativeList)
+
+	t.Run("Completes", func(t *testing.T) {
+		got, err := secure.ListFromProtoContext(context.Background(), protoListPb)
+		require.NoError(t, err)
+		assert.Equal(t, nativeList, got)
+	})
+
+	t.Run("Cancelled mid-conversion", func(t *testing.T) {
+		got, err := secure.ListFromProtoContext(&cancelAfter{Context: context.Background(), n: 3}, protoListPb)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Conversion errors keep their index", func(t *testing.T) {
+		bad := secure.ListToProto(nativeList)
+		bad.Envelopes[70].RecipientId = "not-a-urn:x"
+		_, err := secure.ListFromProtoContext(context.Background(), bad)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse envelope at index 70")
+	})
+}
+
+func TestSecureEnvelope_MarshalCanonical(t *testing.T) {
+	env := newTestEnvelope(t)
+	env.IsEphemeral = true
+
+	first, err := env.MarshalCanonical()
+	require.NoError(t, err)
+	for range 100 {
+		again, err := env.MarshalCanonical()
+		require.NoError(t, err)
+		require.Equal(t, string(first), string(again))
+	}
+	assert.Equal(t,
+		`{"encryptedData":"AQID","encryptedSymmetricKey":"BAUG","isEphemeral":true,"priority":0,"recipientId":"urn:contacts:user:recipient-bob","schemaVersion":1,"signature":"BwgJ"}`,
+		string(first))
+}
+
+func TestSecureEnvelope_FromProto_FieldError(t *testing.T) {
+	protoPb := secure.ToProto(newTestEnvelope(t))
+	protoPb.RecipientId = "urn:too:many:parts:here"
+
+	_, err := secure.FromProto(protoPb)
+	var fe *validation.FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "recipientId", fe.Field())
+	assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+}
+
+func TestSecureEnvelope_JSON_PooledNoLeak(t *testing.T) {
+	full := newTestEnvelope(t)
+	full.IsEphemeral = true
+	full.Priority = 9
+
+	bare := &secure.SecureEnvelope{RecipientID: full.RecipientID, EncryptedData: []byte{42}, SchemaVersion: secure.CurrentSchemaVersion}
+	bareJSON := `{"recipientId":"urn:contacts:user:recipient-bob","encryptedData":"Kg==","priority":0,"schemaVersion":1}`
+
+	for range 10 {
+		_, err := json.Marshal(full)
+		require.NoError(t, err)
+		data, err := json.Marshal(bare)
+		require.NoError(t, err)
+		require.JSONEq(t, bareJSON, string(data))
+
+		var got secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, *bare, got)
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				env := *full
+				env.Priority = int32(i)
+				for range 200 {
+					data, err := json.Marshal(env)
+					if !assert.NoError(t, err) {
+						return
+					}
+					var got secure.SecureEnvelope
+					if !assert.NoError(t, json.Unmarshal(data, &got)) {
+						return
+					}
+					if !assert.Equal(t, env, got) {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func newBenchEnvelope(b *testing.B) *secure.SecureEnvelope {
+	b.Helper()
+	recipientURN, err := urn.Parse("urn:sm:user:recipient-bob")
+	require.NoError(b, err)
+	return &secure.SecureEnvelope{
+		RecipientID:           recipientURN,
+		EncryptedData:         make([]byte, 1024),
+		EncryptedSymmetricKey: make([]byte, 256),
+		Signature:             make([]byte, 64),
+		Priority:              5,
+	}
+}
+
+func BenchmarkSecureEnvelope_MarshalJSON(b *testing.B) {
+	env := newBenchEnvelope(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := env.MarshalJSON(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSecureEnvelope_MarshalJSON_Unpooled is the baseline for
+// BenchmarkSecureEnvelope_MarshalJSON: a fresh SecureEnvelopePb per call.
+func BenchmarkSecureEnvelope_MarshalJSON_Unpooled(b *testing.B) {
+	env := newBenchEnvelope(b)
+	opts := protojson.MarshalOptions{}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := opts.Marshal(secure.ToProto(env)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSecureEnvelope_UnmarshalJSON(b *testing.B) {
+	data, err := newBenchEnvelope(b).MarshalJSON()
+	require.NoError(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		var env secure.SecureEnvelope
+		if err := env.UnmarshalJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSecureEnvelope_Msgpack_RoundTrip(t *testing.T) {
+	original := newTestEnvelope(t)
+	original.IsEphemeral = true
+	original.Priority = 7
+
+	data, err := msgpack.Marshal(original)
+	require.NoError(t, err)
+
+	// Byte fields are msgpack bin, not base64 strings
+	var raw map[string]any
+	require.NoError(t, msgpack.Unmarshal(data, &raw))
+	assert.Equal(t, []byte{1, 2, 3}, raw["encryptedData"])
+	assert.Equal(t, "urn:contacts:user:recipient-bob", raw["recipientId"])
+
+	var got secure.SecureEnvelope
+	require.NoError(t, msgpack.Unmarshal(data, &got))
+	assert.Equal(t, *original, got)
+
+	t.Run("Invalid recipient", func(t *testing.T) {
+		bad, err := msgpack.Marshal(map[string]any{"recipientId": "urn:a:b"})
+		require.NoError(t, err)
+		assert.ErrorIs(t, msgpack.Unmarshal(bad, &got), urn.ErrInvalidFormat)
+	})
+}
+
+func TestSecureEnvelope_CBOR_RoundTrip(t *testing.T) {
+	original := newTestEnvelope(t)
+	original.Priority = 3
+
+	data, err := cbor.Marshal(original)
+	require.NoError(t, err)
+
+	// Byte fields are CBOR byte strings, the recipient a text string
+	var raw map[string]any
+	require.NoError(t, cbor.Unmarshal(data, &raw))
+	assert.Equal(t, []byte{4, 5, 6}, raw["encryptedSymmetricKey"])
+	assert.Equal(t, "urn:contacts:user:recipient-bob", raw["recipientId"])
+
+	var got secure.SecureEnvelope
+	require.NoError(t, cbor.Unmarshal(data, &got))
+	assert.Equal(t, *original, got)
+
+	t.Run("Zero value", func(t *testing.T) {
+		data, err := cbor.Marshal(secure.SecureEnvelope{})
+		require.NoError(t, err)
+		var got secure.SecureEnvelope
+		require.NoError(t, cbor.Unmarshal(data, &got))
+		assert.Equal(t, secure.SecureEnvelope{SchemaVersion: secure.CurrentSchemaVersion}, got)
+
+		got = *original
+		require.NoError(t, cbor.Unmarshal([]byte{0xf6}, &got))
+		assert.Equal(t, secure.SecureEnvelope{}, got)
+	})
+}
+
+func TestSecureEnvelope_AssociatedData_RoundTrip(t *testing.T) {
+	withAAD := newTestEnvelope(t)
+	withAAD.AssociatedData = []byte("urn:contacts:user:recipient-bob|text/plain")
+
+	t.Run("JSON", func(t *testing.T) {
+		for _, env := range []*secure.SecureEnvelope{newTestEnvelope(t), withAAD} {
+			data, err := json.Marshal(env)
+			require.NoError(t, err)
+
+			var m map[string]any
+			require.NoError(t, json.Unmarshal(data, &m))
+			if env.AssociatedData == nil {
+				assert.NotContains(t, m, "associatedData")
+			} else {
+				assert.Equal(t, "dXJuOmNvbnRhY3RzOnVzZXI6cmVjaXBpZW50LWJvYnx0ZXh0L3BsYWlu", m["associatedData"])
+			}
+
+			var got secure.SecureEnvelope
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, *env, got)
+		}
+	})
+
+	t.Run("Binary encodings", func(t *testing.T) {
+		data, err := msgpack.Marshal(withAAD)
+		require.NoError(t, err)
+		var fromMsgpack secure.SecureEnvelope
+		require.NoError(t, msgpack.Unmarshal(data, &fromMsgpack))
+		assert.Equal(t, *withAAD, fromMsgpack)
+
+		data, err = cbor.Marshal(withAAD)
+		require.NoError(t, err)
+		var fromCBOR secure.SecureEnvelope
+		require.NoError(t, cbor.Unmarshal(data, &fromCBOR))
+		assert.Equal(t, *withAAD, fromCBOR)
+	})
+
+	t.Run("Lists keep it", func(t *testing.T) {
+		list := secure.SecureEnvelopeList{Envelopes: []*secure.SecureEnvelope{newTestEnvelope(t), withAAD}}
+		data, err := json.Marshal(list)
+		require.NoError(t, err)
+		var got secure.SecureEnvelopeList
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, list, got)
+	})
+
+	t.Run("Not carried by the proto", func(t *testing.T) {
+		native, err := secure.FromProto(secure.ToProto(withAAD))
+		require.NoError(t, err)
+		assert.Nil(t, native.AssociatedData)
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		var got secure.SecureEnvelope
+		input := `{"recipientId":"urn:sm:user:bob","encryptedData":"AQID","associatedData":"AQ-_"}`
+		require.NoError(t, got.UnmarshalLenientJSON([]byte(input)))
+		assert.Equal(t, []byte{1, 15, 191}, got.AssociatedData)
+	})
+}
+
+func TestSecureEnvelope_ContentType(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		for _, contentType := range []string{secure.ContentTypeUnknown, secure.ContentTypeText, secure.ContentTypeControl} {
+			env := newTestEnvelope(t)
+			env.ContentType = contentType
+
+			data, err := json.Marshal(env)
+			require.NoError(t, err)
+			if contentType == secure.ContentTypeUnknown {
+				assert.NotContains(t, string(data), "contentType")
+			} else {
+				assert.Contains(t, string(data), `"contentType":"`+contentType+`"`)
+			}
+
+			var fromJSON secure.SecureEnvelope
+			require.NoError(t, json.Unmarshal(data, &fromJSON))
+			assert.Equal(t, *env, fromJSON)
+
+			data, err = msgpack.Marshal(env)
+			require.NoError(t, err)
+			var fromMsgpack secure.SecureEnvelope
+			require.NoError(t, msgpack.Unmarshal(data, &fromMsgpack))
+			assert.Equal(t, *env, fromMsgpack)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		assert.NoError(t, env.Validate(), "unknown content type is allowed")
+
+		env.ContentType = secure.ContentTypeJPEG
+		assert.NoError(t, env.Validate())
+
+		env.ContentType = "text/plain\r\nX-Injected: 1"
+		err := env.Validate()
+		require.ErrorIs(t, err, secure.ErrInvalidEnvelope)
+		fieldErr, ok := validation.AsFieldError(err)
+		require.True(t, ok)
+		assert.Equal(t, "contentType", fieldErr.Field())
+	})
+
+	t.Run("Validate requires a recipient", func(t *testing.T) {
+		err := secure.SecureEnvelope{ContentType: secure.ContentTypeText}.Validate()
+		require.ErrorIs(t, err, secure.ErrInvalidEnvelope)
+		fieldErr, ok := validation.AsFieldError(err)
+		require.True(t, ok)
+		assert.Equal(t, "recipientId", fieldErr.Field())
+	})
+}
+
+func TestSecureEnvelope_SchemaVersion(t *testing.T) {
+	t.Run("Legacy envelope is defaulted", func(t *testing.T) {
+		legacy := `{"recipientId":"urn:contacts:user:recipient-bob","encryptedData":"AQID"}`
+		var got secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal([]byte(legacy), &got))
+		assert.Equal(t, secure.CurrentSchemaVersion, got.SchemaVersion)
+
+		native, err := secure.FromProto(&secure.SecureEnvelopePb{RecipientId: "urn:contacts:user:recipient-bob"})
+		require.NoError(t, err)
+		assert.Equal(t, secure.CurrentSchemaVersion, native.SchemaVersion)
+		assert.NoError(t, native.RequireVersion(secure.CurrentSchemaVersion))
+	})
+
+	t.Run("Explicit version", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		env.SchemaVersion = 7
+
+		data, err := json.Marshal(env)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"schemaVersion":7`)
+
+		var got secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, int32(7), got.SchemaVersion)
+
+		data, err = msgpack.Marshal(env)
+		require.NoError(t, err)
+		var fromMsgpack secure.SecureEnvelope
+		require.NoError(t, msgpack.Unmarshal(data, &fromMsgpack))
+		assert.Equal(t, int32(7), fromMsgpack.SchemaVersion)
+	})
+
+	t.Run("RequireVersion", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		env.SchemaVersion = 2
+		assert.NoError(t, env.RequireVersion(1))
+		assert.NoError(t, env.RequireVersion(2))
+		assert.ErrorIs(t, env.RequireVersion(3), secure.ErrUnsupportedSchemaVersion)
+	})
+}
+
+func TestSecureEnvelopeList_Page(t *testing.T) {
+	list := &secure.SecureEnvelopeList{}
+	for i := range 10 {
+		env := newTestEnvelope(t)
+		env.Priority = int32(i)
+		list.Envelopes = append(list.Envelopes, env)
+	}
+
+	for _, limit := range []int{1, 3, 4, 10, 25} {
+		seen := make(map[int32]int)
+		cursor, pages := "", 0
+		for {
+			page, next, err := list.Page(cursor, limit)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Envelopes), limit)
+			for _, env := range page.Envelopes {
+				seen[env.Priority]++
+			}
+			pages++
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		assert.Len(t, seen, 10, "limit %d", limit)
+		for priority, count := range seen {
+			assert.Equal(t, 1, count, "envelope %d with limit %d", priority, limit)
+		}
+		assert.Equal(t, (10+limit-1)/limit, pages, "limit %d", limit)
+	}
+
+	t.Run("Empty list", func(t *testing.T) {
+		page, next, err := (&secure.SecureEnvelopeList{}).Page("", 5)
+		require.NoError(t, err)
+		assert.Empty(t, page.Envelopes)
+		assert.Empty(t, next)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		for _, cursor := range []string{"not base64!", "YWJj", "LTE", "OTk"} { // "abc", "-1", "99"
+			_, _, err := list.Page(cursor, 3)
+			assert.ErrorIs(t, err, secure.ErrInvalidCursor, cursor)
+		}
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		_, _, err := list.Page("", 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestSecureEnvelope_UnmarshalJSON_AllOrNothing(t *testing.T) {
+	for name, input := range map[string]string{
+		"Malformed JSON":        `{"recipientId":`,
+		"Bad recipient":         `{"recipientId":"urn:too:many:parts:here","encryptedData":"AQID"}`,
+		"Bad base64":            `{"recipientId":"urn:sm:user:x","encryptedData":"***"}`,
+		"Bad extension field":   `{"recipientId":"urn:sm:user:x","schemaVersion":"one"}`,
+		"Wrong top-level type":  `["urn:sm:user:x"]`,
+		"Priority out of range": `{"recipientId":"urn:sm:user:x","priority":4294967296}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnvelope(t)
+			env.AssociatedData = []byte("aad")
+			before := *env
+
+			require.Error(t, json.Unmarshal([]byte(input), env))
+			assert.Equal(t, before, *env)
+		})
+	}
+}
+
+// FuzzSecureEnvelopeUnmarshal checks that UnmarshalJSON never panics, leaves
+// the envelope untouched on error, and that whatever it accepts survives a
+// marshal/unmarshal round trip.
+func FuzzSecureEnvelopeUnmarshal(f *testing.F) {
+	for _, seed := range []string{
+		`{}`,
+		`null`,
+		`{"recipientId":"urn:contacts:user:recipient-bob","encryptedData":"AQID","encryptedSymmetricKey":"BAUG","signature":"BwgJ","priority":3}`,
+		`{"recipientId":"urn:sm:user:x","associatedData":"YWFk","contentType":"text/plain","compression":"gzip","schemaVersion":2}`,
+		`{"recipientId":"urn:sm:thread:t1/message:m2"}`,
+		`{"recipientId":"bare-legacy-id"}`,
+		`{"recipientId":"urn:sm:user:"}`,
+		`{"recipientId":1}`,
+		`{"encryptedData":"AQ"}`,
+		`{"priority":-1}`,
+		`{"isEphemeral":"true"}`,
+		`{"schemaVersion":-5}`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sentinel := secure.SecureEnvelope{Signature: []byte("sentinel"), Priority: 42}
+		env := sentinel
+		if err := env.UnmarshalJSON(data); err != nil {
+			require.Equal(t, sentinel, env, "a failed unmarshal must not modify the envelope")
+			return
+		}
+
+		out, err := json.Marshal(env)
+		require.NoError(t, err)
+		var again secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal(out, &again), "re-reading %s", out)
+		assert.Equal(t, env, again)
+	})
+}
+
+func TestSecureEnvelope_LogValue(t *testing.T) {
+	env := newTestEnvelope(t)
+	env.AssociatedData = []byte("aad-secret")
+	env.ContentType = secure.ContentTypeText
+
+	attrs := map[string]slog.Value{}
+	for _, a := range env.LogValue().Group() {
+		attrs[a.Key] = a.Value
+	}
+	assert.Equal(t, "urn:contacts:user:recipient-bob", attrs["recipientId"].String())
+	assert.Equal(t, int64(3), attrs["encryptedDataLen"].Int64())
+	assert.Equal(t, int64(3), attrs["encryptedSymmetricKeyLen"].Int64())
+	assert.Equal(t, int64(10), attrs["associatedDataLen"].Int64())
+	assert.True(t, attrs["signed"].Bool())
+	assert.Equal(t, secure.ContentTypeText, attrs["contentType"].String())
+	for _, secret := range []string{"encryptedData", "encryptedSymmetricKey", "signature", "associatedData"} {
+		assert.NotContains(t, attrs, secret)
+	}
+
+	t.Run("Handler output has no secret bytes", func(t *testing.T) {
+		var buf bytes.Buffer
+		slog.New(slog.NewJSONHandler(&buf, nil)).Info("stored", "envelope", env)
+		out := buf.String()
+		assert.Contains(t, out, `"envelope":{"recipientId":"urn:contacts:user:recipient-bob"`)
+		// The base64 forms of the byte fields, as json.Marshal would write them
+		for _, secret := range []string{"AQID", "BAUG", "BwgJ", "YWFkLXNlY3JldA", "aad-secret"} {
+			assert.NotContains(t, out, secret)
+		}
+	})
+}
+
+func TestSecureEnvelopeList_SplitBatches(t *testing.T) {
+	// Every test envelope has an EstimatedSize of 40
+	newList := func(n int) *secure.SecureEnvelopeList {
+		list := &secure.SecureEnvelopeList{}
+		for i := range n {
+			env := newTestEnvelope(t)
+			env.Priority = int32(i)
+			list.Envelopes = append(list.Envelopes, env)
+		}
+		return list
+	}
+	priorities := func(batches []*secure.SecureEnvelopeList) [][]int32 {
+		var out [][]int32
+		for _, b := range batches {
+			var ps []int32
+			for _, env := range b.Envelopes {
+				ps = append(ps, env.Priority)
+			}
+			out = append(out, ps)
+		}
+		return out
+	}
+
+	t.Run("Count bound", func(t *testing.T) {
+		batches := newList(7).SplitBatches(3, 0)
+		assert.Equal(t, [][]int32{{0, 1, 2}, {3, 4, 5}, {6}}, priorities(batches))
+	})
+
+	t.Run("Byte bound", func(t *testing.T) {
+		batches := newList(5).SplitBatches(0, 100)
+		assert.Equal(t, [][]int32{{0, 1}, {2, 3}, {4}}, priorities(batches))
+	})
+
+	t.Run("Tighter limit wins", func(t *testing.T) {
+		assert.Equal(t, [][]int32{{0, 1}, {2, 3}}, priorities(newList(4).SplitBatches(3, 80)))
+		assert.Equal(t, [][]int32{{0}, {1}, {2}}, priorities(newList(3).SplitBatches(1, 1000)))
+	})
+
+	t.Run("Oversized envelope gets its own batch", func(t *testing.T) {
+		list := newList(4)
+		list.Envelopes[1].EncryptedData = make([]byte, 500)
+		batches := list.SplitBatches(10, 100)
+		assert.Equal(t, [][]int32{{0}, {1}, {2, 3}}, priorities(batches))
+	})
+
+	t.Run("No limits", func(t *testing.T) {
+		assert.Equal(t, [][]int32{{0, 1, 2}}, priorities(newList(3).SplitBatches(0, 0)))
+	})
+
+	t.Run("Empty list", func(t *testing.T) {
+		assert.Empty(t, (&secure.SecureEnvelopeList{}).SplitBatches(3, 100))
+	})
+
+	t.Run("Batches cannot grow into each other", func(t *testing.T) {
+		batches := newList(4).SplitBatches(2, 0)
+		batches[0].Envelopes = append(batches[0].Envelopes, newTestEnvelope(t))
+		assert.Equal(t, int32(2), batches[1].Envelopes[0].Priority)
+	})
+}
+
+func TestSecureEnvelope_EstimatedSize(t *testing.T) {
+	env := newTestEnvelope(t)
+	// recipient (31) + encryptedData (3) + encryptedSymmetricKey (3) + signature (3)
+	assert.Equal(t, 40, env.EstimatedSize())
+	assert.Equal(t, 0, (&secure.SecureEnvelope{}).EstimatedSize())
+
+	for _, size := range []int{0, 1 << 10, 64 << 10} {
+		env := newTestEnvelope(t)
+		env.EncryptedData = bytes.Repeat([]byte{0xab}, size)
+		env.AssociatedData = []byte("aad")
+		env.ContentType = secure.ContentTypeJSON
+		estimate := env.EstimatedSize()
+
+		jsonBytes, err := json.Marshal(env)
+		require.NoError(t, err)
+		msgpackBytes, err := msgpack.Marshal(env)
+		require.NoError(t, err)
+
+		// The estimate never exceeds a wire form, and trails JSON by no more
+		// than base64's third plus a fixed allowance for member names.
+		assert.LessOrEqual(t, estimate, len(msgpackBytes), "size %d", size)
+		assert.LessOrEqual(t, estimate, len(jsonBytes), "size %d", size)
+		assert.LessOrEqual(t, len(jsonBytes), estimate*4/3+256, "size %d", size)
+	}
+}
+
+func TestSecureEnvelope_UnmarshalJSONPreserveUnknown(t *testing.T) {
+	// A newer producer's envelope, with members this version does not know
+	// and one known member under its proto name.
+	input := `{"recipient_id":"urn:sm:user:bob","encryptedData":"AQID","contentType":"text/plain",` +
+		`"futureTtl":3600,"futureRouting":{"hops":["eu","us"]}}`
+
+	t.Run("Future fields survive a round trip", func(t *testing.T) {
+		var env secure.SecureEnvelope
+		require.NoError(t, env.UnmarshalJSONPreserveUnknown([]byte(input)))
+		assert.Equal(t, "urn:sm:user:bob", env.RecipientID.String())
+		assert.Equal(t, secure.ContentTypeText, env.ContentType)
+
+		out, err := json.Marshal(env)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"recipientId":"urn:sm:user:bob","encryptedData":"AQID","contentType":"text/plain",`+
+			`"priority":0,"schemaVersion":1,"futureTtl":3600,"futureRouting":{"hops":["eu","us"]}}`, string(out))
+
+		// And again, through a second forwarding hop
+		var again secure.SecureEnvelope
+		require.NoError(t, again.UnmarshalJSONPreserveUnknown(out))
+		out2, err := json.Marshal(again)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(out), string(out2))
+	})
+
+	t.Run("Default mode drops them", func(t *testing.T) {
+		var env secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal([]byte(input), &env))
+		out, err := json.Marshal(env)
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "future")
+	})
+
+	t.Run("All-or-nothing", func(t *testing.T) {
+		env := *newTestEnvelope(t)
+		before := env
+		assert.Error(t, env.UnmarshalJSONPreserveUnknown([]byte(`{"recipientId":"urn:sm:user","futureTtl":1}`)))
+		assert.Equal(t, before, env)
+	})
+}
+
+func TestSecureEnvelope_WithRecipient(t *testing.T) {
+	original := newTestEnvelope(t)
+	original.AssociatedData = []byte("aad")
+	originalRecipient := original.RecipientID
+
+	internal, err := urn.Parse("urn:relay:user:internal-7")
+	require.NoError(t, err)
+	relayed := original.WithRecipient(internal)
+
+	assert.Equal(t, internal, relayed.RecipientID)
+	assert.Equal(t, originalRecipient, original.RecipientID, "the original recipient is unchanged")
+	assert.NotSame(t, original, relayed)
+
+	// Everything else is carried over, sharing the byte slices
+	relayed.RecipientID = originalRecipient
+	assert.Equal(t, original, relayed)
+	assert.Same(t, &original.EncryptedData[0], &relayed.EncryptedData[0])
+	assert.Same(t, &original.AssociatedData[0], &relayed.AssociatedData[0])
 }
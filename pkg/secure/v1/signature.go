@@ -0,0 +1,111 @@
+package secure
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// ErrBadSignature is returned by Verify when Signature does not match the
+// envelope's signed fields.
+var ErrBadSignature = errors.New("bad envelope signature")
+
+// signatureContext prefixes the signed bytes so an envelope signature cannot
+// be replayed as a signature over some other structure.
+const signatureContext = "tinywide.secure.v1.SecureEnvelope\x00"
+
+// SignedBytes returns the canonical serialization that Sign and Verify
+// cover, for external signers. It does not depend on the JSON or proto wire
+// formats. The layout is:
+//
+//	"tinywide.secure.v1.SecureEnvelope" 0x00   (34 bytes, no length prefix)
+//	len(recipient)             uint32 big-endian
+//	recipient                  RecipientID.String() as UTF-8; empty when zero
+//	len(EncryptedData)         uint32 big-endian
+//	EncryptedData
+//	len(EncryptedSymmetricKey) uint32 big-endian
+//	EncryptedSymmetricKey
+//	len(AssociatedData)        uint32 big-endian
+//	AssociatedData
+//	len(ContentType)           uint32 big-endian
+//	ContentType                as UTF-8
+//	len(Compression)           uint32 big-endian
+//	Compression                as UTF-8
+//
+// ContentType and Compression are signed because they decide how the
+// recipient reads the decrypted payload: flipping Compression would feed
+// the plaintext to a decompressor, or skip one.
+//
+// The length prefixes keep field boundaries unambiguous; a nil and an empty
+// field are both a zero length. A field too long for its prefix is an error.
+//
+// ToProto drops AssociatedData, ContentType and Compression, so an envelope
+// that sets them only verifies after a JSON, msgpack or CBOR round trip.
+func (se *SecureEnvelope) SignedBytes() ([]byte, error) {
+	recipient := se.RecipientID.String()
+	fields := [][]byte{
+		[]byte(recipient),
+		se.EncryptedData,
+		se.EncryptedSymmetricKey,
+		se.AssociatedData,
+		[]byte(se.ContentType),
+		[]byte(se.Compression),
+	}
+
+	size := len(signatureContext)
+	for _, f := range fields {
+		if uint64(len(f)) > math.MaxUint32 {
+			return nil, fmt.Errorf("%w: signed field is %d bytes, limit is %d", ErrInvalidEnvelope, len(f), uint32(math.MaxUint32))
+		}
+		size += 4 + len(f)
+	}
+	buf := make([]byte, 0, size)
+	buf = append(buf, signatureContext...)
+	for _, f := range fields {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(f)))
+		buf = append(buf, f...)
+	}
+	return buf, nil
+}
+
+// Sign signs the envelope's signed fields with privKey, the sender's ed25519
+// private key, and stores the result in Signature. It uses the same
+// serialization (SignedBytes) as Verify, so a signed envelope verifies
+// against the matching public key until one of those fields changes.
+func (se *SecureEnvelope) Sign(privKey ed25519.PrivateKey) error {
+	if len(privKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("%w: private key is %d bytes, expected %d", keys.ErrInvalidKey, len(privKey), ed25519.PrivateKeySize)
+	}
+	signed, err := se.SignedBytes()
+	if err != nil {
+		return err
+	}
+	se.Signature = ed25519.Sign(privKey, signed)
+	return nil
+}
+
+// Verify checks Signature against sigKey, the sender's ed25519 public key
+// (keys.PublicKeys.SigKey). The recipient, ciphertext, wrapped key,
+// associated data, content type and compression are signed; the routing
+// hints (IsEphemeral, Priority) are not. A malformed key wraps
+// keys.ErrInvalidKey and a mismatch wraps ErrBadSignature.
+func (se *SecureEnvelope) Verify(sigKey []byte) error {
+	if len(sigKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: sigKey is %d bytes, expected %d", keys.ErrInvalidKey, len(sigKey), ed25519.PublicKeySize)
+	}
+	if len(se.Signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: signature is %d bytes, expected %d", ErrBadSignature, len(se.Signature), ed25519.SignatureSize)
+	}
+	signed, err := se.SignedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(sigKey), signed, se.Signature) {
+		return fmt.Errorf("%w: signature does not match envelope for %s", ErrBadSignature, se.RecipientID)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package secure_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"github.com/tinywideclouds/go-platform/pkg/secure/v1"
+)
+
+// signedPayload mirrors the package's canonical serialization so the tests
+// can sign envelopes with the standard library alone.
+func signedPayload(env *secure.SecureEnvelope) []byte {
+	buf := []byte("tinywide.secure.v1.SecureEnvelope\x00")
+	for _, f := range [][]byte{
+		[]byte(env.RecipientID.String()),
+		env.EncryptedData,
+		env.EncryptedSymmetricKey,
+		env.AssociatedData,
+		[]byte(env.ContentType),
+		[]byte(env.Compression),
+	} {
+		n := len(f)
+		buf = append(buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+		buf = append(buf, f...)
+	}
+	return buf
+}
+
+func TestSecureEnvelope_Verify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sender := keys.PublicKeys{EncKey: make([]byte, 32), SigKey: pub}
+
+	newSigned := func(t *testing.T) *secure.SecureEnvelope {
+		env := newTestEnvelope(t)
+		env.AssociatedData = []byte("aad")
+		env.Signature = ed25519.Sign(priv, signedPayload(env))
+		return env
+	}
+
+	t.Run("Valid signature", func(t *testing.T) {
+		env := newSigned(t)
+		assert.NoError(t, env.Verify(sender.SigKey))
+
+		// Unsigned routing hints can change without invalidating it.
+		env.Priority = 9
+		env.IsEphemeral = true
+		assert.NoError(t, env.Verify(sender.SigKey))
+	})
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		for name, tamper := range map[string]func(*secure.SecureEnvelope){
+			"encryptedData":         func(e *secure.SecureEnvelope) { e.EncryptedData[0] ^= 1 },
+			"encryptedSymmetricKey": func(e *secure.SecureEnvelope) { e.EncryptedSymmetricKey = append(e.EncryptedSymmetricKey, 0) },
+			"associatedData":        func(e *secure.SecureEnvelope) { e.AssociatedData = nil },
+			"contentType":           func(e *secure.SecureEnvelope) { e.ContentType = secure.ContentTypeText },
+			"compression":           func(e *secure.SecureEnvelope) { e.Compression = secure.CompressionGzip },
+		} {
+			t.Run(name, func(t *testing.T) {
+				env := newSigned(t)
+				tamper(env)
+				assert.ErrorIs(t, env.Verify(sender.SigKey), secure.ErrBadSignature)
+			})
+		}
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		assert.ErrorIs(t, newSigned(t).Verify(otherPub), secure.ErrBadSignature)
+	})
+
+	t.Run("Malformed key", func(t *testing.T) {
+		assert.ErrorIs(t, newSigned(t).Verify(pub[:16]), keys.ErrInvalidKey)
+	})
+
+	t.Run("Missing signature", func(t *testing.T) {
+		env := newSigned(t)
+		env.Signature = nil
+		assert.ErrorIs(t, env.Verify(sender.SigKey), secure.ErrBadSignature)
+	})
+}
+
+func TestSecureEnvelope_SignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("Round trip", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		env.Signature = nil
+		require.NoError(t, env.Sign(priv))
+		assert.NoError(t, env.Verify(pub))
+		assert.Equal(t, ed25519.Sign(priv, signedPayload(env)), env.Signature, "Sign must use the canonical serialization")
+	})
+
+	t.Run("Mutated after signing", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		require.NoError(t, env.Sign(priv))
+		mallory, err := urn.Parse("urn:contacts:user:mallory")
+		require.NoError(t, err)
+		env.RecipientID = mallory
+		assert.ErrorIs(t, env.Verify(pub), secure.ErrBadSignature)
+	})
+
+	t.Run("Malformed private key", func(t *testing.T) {
+		env := newTestEnvelope(t)
+		before := env.Signature
+		assert.ErrorIs(t, env.Sign(priv[:32]), keys.ErrInvalidKey)
+		assert.Equal(t, before, env.Signature)
+	})
+}
+
+func TestSecureEnvelope_SignedBytes(t *testing.T) {
+	recipient, err := urn.Parse("urn:sm:user:bob")
+	require.NoError(t, err)
+	env := &secure.SecureEnvelope{
+		RecipientID:           recipient,
+		EncryptedData:         []byte{1, 2, 3},
+		EncryptedSymmetricKey: []byte{4},
+		ContentType:           "text/plain",
+		Signature:             []byte{9, 9},
+		IsEphemeral:           true,
+	}
+
+	signed, err := env.SignedBytes()
+	require.NoError(t, err)
+
+	t.Run("Documented layout", func(t *testing.T) {
+		want := hex.EncodeToString([]byte("tinywide.secure.v1.SecureEnvelope\x00")) +
+			"0000000f" + hex.EncodeToString([]byte("urn:sm:user:bob")) +
+			"00000003" + "010203" +
+			"00000001" + "04" +
+			"00000000" +
+			"0000000a" + hex.EncodeToString([]byte("text/plain")) +
+			"00000000"
+		assert.Equal(t, want, hex.EncodeToString(signed))
+		assert.Equal(t, signedPayload(env), signed)
+	})
+
+	t.Run("Stable across calls and wire formats", func(t *testing.T) {
+		for range 3 {
+			again, err := env.SignedBytes()
+			require.NoError(t, err)
+			assert.Equal(t, signed, again)
+		}
+
+		data, err := json.Marshal(env)
+		require.NoError(t, err)
+		var decoded secure.SecureEnvelope
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		fromJSON, err := decoded.SignedBytes()
+		require.NoError(t, err)
+		assert.Equal(t, signed, fromJSON)
+
+		// SecureEnvelopePb has no ContentType, so only the proto fields survive.
+		protoOnly := *env
+		protoOnly.ContentType = ""
+		want, err := protoOnly.SignedBytes()
+		require.NoError(t, err)
+		fromProto, err := secure.FromProto(secure.ToProto(&protoOnly))
+		require.NoError(t, err)
+		viaProto, err := fromProto.SignedBytes()
+		require.NoError(t, err)
+		assert.Equal(t, want, viaProto)
+	})
+
+	t.Run("Unsigned fields are excluded", func(t *testing.T) {
+		changed := *env
+		changed.Signature = nil
+		changed.IsEphemeral = false
+		got, err := changed.SignedBytes()
+		require.NoError(t, err)
+		assert.Equal(t, signed, got)
+	})
+
+	t.Run("Nil and empty fields match", func(t *testing.T) {
+		empty := *env
+		empty.AssociatedData = []byte{}
+		got, err := empty.SignedBytes()
+		require.NoError(t, err)
+		assert.Equal(t, signed, got)
+	})
+}
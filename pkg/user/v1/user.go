@@ -1,30 +1,66 @@
 package name
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
 	userv1 "github.com/tinywideclouds/gen-platform/go/types/user/v1"
-	// --- NEW IMPORTS ---
+	"github.com/tinywideclouds/go-platform/internal/convert"
+	"github.com/tinywideclouds/go-platform/internal/jsonext"
+	"github.com/tinywideclouds/go-platform/internal/openapi"
+	"github.com/tinywideclouds/go-platform/internal/yamljson"
+	"github.com/tinywideclouds/go-platform/pkg/facade/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"gopkg.in/yaml.v3"
 )
 
-// --- Marshal/Unmarshal Options ---
-var (
-	// protojsonMarshalOptions tells protojson to use camelCase (json_name)
-	protojsonMarshalOptions = &protojson.MarshalOptions{
-		UseProtoNames:   false, // Use camelCase
-		EmitUnpopulated: false,
-	}
+// ErrInvalidEmail is returned by ValidateEmail for a malformed address.
+var ErrInvalidEmail = errors.New("invalid email address")
 
-	// protojsonUnmarshalOptions tells protojson to ignore unknown fields.
-	protojsonUnmarshalOptions = &protojson.UnmarshalOptions{
-		DiscardUnknown: true,
-	}
+// Status is the lifecycle state of a user account.
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
 )
 
 type User struct {
+	// ID is serialized as the URN string, or null when zero.
+	ID urn.URN `json:"id,omitempty"`
 	// Updated JSON tags to camelCase
 	Alias string `json:"alias,omitempty"`
 	Name  string `json:"name,omitempty"`
 	Email string `json:"email,omitempty"`
+	// AvatarURL is carried by the proto's profile_url field.
+	AvatarURL string `json:"profileUrl,omitempty"`
+
+	// Phone and Status are not part of UserPb yet: ToProto drops them, and the
+	// JSON facade carries them alongside the protojson fields.
+	Phone  string `json:"phone,omitempty"`
+	Status Status `json:"status,omitempty"`
+}
+
+// userExt holds the User fields that UserPb cannot carry.
+type userExt struct {
+	Phone  string `json:"phone,omitempty"`
+	Status Status `json:"status,omitempty"`
+}
+
+// userExtNullID is userExt for a User with a zero ID, which is written as
+// "id": null rather than omitted or "".
+type userExtNullID struct {
+	ID     json.RawMessage `json:"id"`
+	Phone  string          `json:"phone,omitempty"`
+	Status Status          `json:"status,omitempty"`
 }
 
 // ToProto converts the idiomatic Go struct into its Protobuf representation.
@@ -32,23 +68,44 @@ func ToProto(native *User) *userv1.UserPb {
 	if native == nil {
 		return nil
 	}
-	return &userv1.UserPb{
+	protoPb := &userv1.UserPb{
+		Id:    native.ID.String(),
 		Alias: native.Alias,
 		Name:  native.Name,
 		Email: native.Email,
 	}
+	if native.AvatarURL != "" {
+		avatarURL := native.AvatarURL
+		protoPb.ProfileUrl = &avatarURL
+	}
+	return protoPb
+}
+
+// ToProtoMessage implements facade.Protoer, returning ToProto(&u) as a
+// *UserPb.
+func (u User) ToProtoMessage() proto.Message {
+	return ToProto(&u)
 }
 
+var _ facade.Protoer = User{}
+
 // FromProto converts the Protobuf representation into the idiomatic Go struct.
 func FromProto(proto *userv1.UserPb) (*User, error) {
 	if proto == nil {
 		return nil, nil
 	}
 
+	id, err := urn.Parse(proto.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user ID URN from proto: %w", err)
+	}
+
 	return &User{
-		Alias: proto.Alias,
-		Name:  proto.Name,
-		Email: proto.Email,
+		ID:        id,
+		Alias:     proto.Alias,
+		Name:      proto.Name,
+		Email:     proto.Email,
+		AvatarURL: proto.GetProfileUrl(),
 	}, nil
 }
 
@@ -59,12 +116,44 @@ func FromProto(proto *userv1.UserPb) (*User, error) {
 // REFACTOR: This now has a VALUE RECEIVER (no *).
 // This makes the marshaling robust and linter-friendly.
 func (u User) MarshalJSON() ([]byte, error) {
+	return u.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options, e.g.
+// UseProtoNames and EmitUnpopulated for a debug endpoint. The options apply
+// to phone and status as well as to the UserPb fields.
+func (u User) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
 	// 1. Convert native Go struct to Protobuf struct
 	// Note: We pass a pointer to ToProto
 	protoPb := ToProto(&u)
 
-	// 2. Marshal using our camelCase options
-	return protojsonMarshalOptions.Marshal(protoPb)
+	// 2. Marshal using the given options
+	data, err := opts.Marshal(protoPb)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Append the fields UserPb does not carry, and a zero ID as null in
+	// place of the "" EmitUnpopulated writes
+	var ext any = userExt{Phone: u.Phone, Status: u.Status}
+	if u.ID.IsZero() {
+		if data, err = jsonext.Remove(data, "id"); err != nil {
+			return nil, err
+		}
+		ext = userExtNullID{ID: json.RawMessage("null"), Phone: u.Phone, Status: u.Status}
+	}
+	return jsonext.MergeWith(data, ext, opts)
+}
+
+// MarshalCanonical returns the JSON form with sorted keys and no
+// insignificant whitespace, so equal values always give identical bytes.
+// Use it for hashing and signing rather than MarshalJSON.
+func (u User) MarshalCanonical() ([]byte, error) {
+	data, err := u.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jsonext.Canonical(data)
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -73,7 +162,7 @@ func (u User) MarshalJSON() ([]byte, error) {
 func (u *User) UnmarshalJSON(data []byte) error {
 	var protoPb userv1.UserPb
 	// Use our UnmarshalOptions
-	if err := protojsonUnmarshalOptions.Unmarshal(data, &protoPb); err != nil {
+	if err := convert.UnmarshalOptions().Unmarshal(data, &protoPb); err != nil {
 		return err
 	}
 
@@ -82,11 +171,300 @@ func (u *User) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	var ext userExt
+	if err := jsonext.Unmarshal(data, &ext); err != nil {
+		return err
+	}
+
 	if native != nil {
 		*u = *native
 	} else {
 		*u = User{}
 	}
+	u.Phone = ext.Phone
+	u.Status = ext.Status
 
 	return nil
 }
+
+// MarshalYAML implements the yaml.Marshaler interface using the JSON form.
+func (u User) MarshalYAML() (any, error) {
+	return yamljson.Marshal(u)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface using the JSON form.
+func (u *User) UnmarshalYAML(node *yaml.Node) error {
+	return yamljson.Unmarshal(node, u)
+}
+
+// --- Display ---
+
+// DisplayName returns the best name to show in a UI: the alias, then the
+// name, then the local part of the email. It returns "" only when all three
+// are empty.
+func (u User) DisplayName() string {
+	switch {
+	case u.Alias != "":
+		return u.Alias
+	case u.Name != "":
+		return u.Name
+	}
+	local, _, _ := strings.Cut(u.Email, "@")
+	if local == "" {
+		return u.Email
+	}
+	return local
+}
+
+// --- Partial Updates ---
+
+// Merge applies a sparse PATCH: every non-empty field of patch overwrites the
+// corresponding field of u, and empty fields leave u unchanged.
+//
+// Limitation: an empty value cannot be told apart from an absent one, so
+// Merge can never clear a field. Clearing needs an explicit mechanism.
+func (u *User) Merge(patch *User) {
+	if patch == nil {
+		return
+	}
+	if !patch.ID.IsZero() {
+		u.ID = patch.ID
+	}
+	if patch.Alias != "" {
+		u.Alias = patch.Alias
+	}
+	if patch.Name != "" {
+		u.Name = patch.Name
+	}
+	if patch.Email != "" {
+		u.Email = patch.Email
+	}
+	if patch.AvatarURL != "" {
+		u.AvatarURL = patch.AvatarURL
+	}
+	if patch.Phone != "" {
+		u.Phone = patch.Phone
+	}
+	if patch.Status != "" {
+		u.Status = patch.Status
+	}
+}
+
+// ErrInvalidFieldMask is returned by ApplyFieldMask for a path that does not
+// name a User field.
+var ErrInvalidFieldMask = errors.New("invalid field mask")
+
+// userMaskFields maps each FieldMask path to the User field it selects. The
+// paths are the UserPb field names, plus phone and status.
+var userMaskFields = map[string]func(dst, src *User){
+	"id":          func(dst, src *User) { dst.ID = src.ID },
+	"alias":       func(dst, src *User) { dst.Alias = src.Alias },
+	"name":        func(dst, src *User) { dst.Name = src.Name },
+	"email":       func(dst, src *User) { dst.Email = src.Email },
+	"profile_url": func(dst, src *User) { dst.AvatarURL = src.AvatarURL },
+	"phone":       func(dst, src *User) { dst.Phone = src.Phone },
+	"status":      func(dst, src *User) { dst.Status = src.Status },
+}
+
+// ApplyFieldMask copies the fields named by mask from src to dst. Unlike
+// Merge, a masked field is copied even when it is empty in src, so a mask can
+// clear a field. Paths use the proto field names ("profile_url", not
+// "profileUrl"). All paths are checked before anything is copied, so an
+// error leaves dst unchanged. A nil or empty mask copies nothing.
+func ApplyFieldMask(dst *User, src *User, mask *fieldmaskpb.FieldMask) error {
+	if dst == nil || src == nil {
+		return fmt.Errorf("%w: dst and src must not be nil", ErrInvalidFieldMask)
+	}
+	paths := mask.GetPaths()
+	for _, path := range paths {
+		if _, ok := userMaskFields[path]; !ok {
+			return fmt.Errorf("%w: unknown path %q", ErrInvalidFieldMask, path)
+		}
+	}
+	for _, path := range paths {
+		userMaskFields[path](dst, src)
+	}
+	return nil
+}
+
+// userDiffFields lists each User field by its JSON name with its string form.
+var userDiffFields = []struct {
+	name  string
+	value func(u *User) string
+}{
+	{"id", func(u *User) string { return u.ID.String() }},
+	{"alias", func(u *User) string { return u.Alias }},
+	{"name", func(u *User) string { return u.Name }},
+	{"email", func(u *User) string { return u.Email }},
+	{"profileUrl", func(u *User) string { return u.AvatarURL }},
+	{"phone", func(u *User) string { return u.Phone }},
+	{"status", func(u *User) string { return string(u.Status) }},
+}
+
+// Diff returns the fields that differ between u and other, keyed by JSON
+// field name, as [old, new] pairs with u as old. An empty field is "" and the
+// ID is its URN string. Identical users give an empty map. The values are
+// not redacted.
+func (u User) Diff(other User) map[string][2]string {
+	diff := map[string][2]string{}
+	for _, field := range userDiffFields {
+		before, after := field.value(&u), field.value(&other)
+		if before != after {
+			diff[field.name] = [2]string{before, after}
+		}
+	}
+	return diff
+}
+
+// --- Logging ---
+
+// Redacted returns a copy of u that is safe to log: the email keeps only its
+// first character and domain ("a***@example.com") and the phone only its last
+// two digits ("***23"). Other fields are unchanged.
+func (u User) Redacted() User {
+	u.Email = redactEmail(u.Email)
+	u.Phone = redactPhone(u.Phone)
+	return u
+}
+
+// String implements fmt.Stringer using the redacted form, so a User passed to
+// a logger or fmt verb never prints the raw email or phone.
+func (u User) String() string {
+	r := u.Redacted()
+	return fmt.Sprintf("User{id=%s alias=%q name=%q email=%q profileUrl=%q phone=%q status=%q}",
+		r.ID, r.Alias, r.Name, r.Email, r.AvatarURL, r.Phone, r.Status)
+}
+
+// LogValue implements slog.LogValuer with the same redaction as String, as a
+// group of the non-empty fields keyed by their JSON names.
+func (u User) LogValue() slog.Value {
+	r := u.Redacted()
+	attrs := make([]slog.Attr, 0, len(userDiffFields))
+	for _, field := range userDiffFields {
+		if v := field.value(&r); v != "" {
+			attrs = append(attrs, slog.String(field.name, v))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func redactEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		if email == "" {
+			return ""
+		}
+		return "***"
+	}
+	_, size := utf8.DecodeRuneInString(email)
+	return email[:size] + "***" + email[at:]
+}
+
+func redactPhone(phone string) string {
+	if len(phone) <= 2 {
+		if phone == "" {
+			return ""
+		}
+		return "***"
+	}
+	return "***" + phone[len(phone)-2:]
+}
+
+// --- UserList (List) ---
+
+// UserList is the idiomatic Go struct for a list of users.
+type UserList struct {
+	Users []*User `json:"users,omitempty"`
+}
+
+// userListJSON has UserList's shape without its methods, so encoding/json
+// uses the User facade for each element.
+type userListJSON struct {
+	Users []*User `json:"users,omitempty"`
+}
+
+// ListToProto converts the idiomatic Go list into a slice of Protobuf users.
+// gen-platform has no list message for users, so the slice is the wire form.
+func ListToProto(native *UserList) []*userv1.UserPb {
+	if native == nil {
+		return nil
+	}
+	return convert.List(native.Users, ToProto)
+}
+
+// ListFromProto converts a slice of Protobuf users into the idiomatic Go list.
+func ListFromProto(proto []*userv1.UserPb) (*UserList, error) {
+	if proto == nil {
+		return nil, nil
+	}
+	nativeUsers, err := convert.ListErr(proto, FromProto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user %w", err)
+	}
+	return &UserList{
+		Users: nativeUsers,
+	}, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// Each element is marshaled by the User facade, so the output is camelCase.
+func (ul UserList) MarshalJSON() ([]byte, error) {
+	return ul.MarshalJSONWith(convert.MarshalOptions())
+}
+
+// MarshalJSONWith is MarshalJSON with caller-supplied protojson options,
+// applied to every user.
+func (ul UserList) MarshalJSONWith(opts protojson.MarshalOptions) ([]byte, error) {
+	return jsonext.MarshalList("users", ul.Users, opts)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (ul *UserList) UnmarshalJSON(data []byte) error {
+	var wire userListJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*ul = UserList(wire)
+	return nil
+}
+
+// --- Email ---
+
+// NormalizeEmail trims and lowercases the email so that addresses differing
+// only in case or surrounding whitespace compare equal. It is not applied by
+// UnmarshalJSON; callers normalize explicitly before storing or comparing.
+func (u *User) NormalizeEmail() {
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+}
+
+// ValidateEmail checks that Email is a bare addr-spec (no display name) with
+// a dotted domain, e.g. "alice@example.com".
+func (u User) ValidateEmail() error {
+	addr, err := mail.ParseAddress(u.Email)
+	if err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidEmail, u.Email, err)
+	}
+	if addr.Address != u.Email || addr.Name != "" {
+		return fmt.Errorf("%w: %q is not a bare address", ErrInvalidEmail, u.Email)
+	}
+	domain := u.Email[strings.LastIndex(u.Email, "@")+1:]
+	if !strings.Contains(domain, ".") || strings.HasSuffix(domain, ".") {
+		return fmt.Errorf("%w: %q has no valid domain", ErrInvalidEmail, u.Email)
+	}
+	return nil
+}
+
+// --- Schema ---
+
+// OpenAPISchema returns the OpenAPI 3.1 schema object for the JSON form of
+// User: the proto descriptor's fields plus the fields UserPb does not carry.
+func (u User) OpenAPISchema() (map[string]any, error) {
+	schema := openapi.Schema((&userv1.UserPb{}).ProtoReflect().Descriptor())
+	props := openapi.Properties(schema)
+	props["phone"] = map[string]any{"type": "string"}
+	props["status"] = map[string]any{
+		"type": "string",
+		"enum": []string{string(StatusActive), string(StatusSuspended)},
+	}
+	return schema, nil
+}
@@ -1,11 +1,21 @@
 package name
 
 import (
+	"bytes"
 	"encoding/json" // We use the standard 'json' lib to test the interface
+	"fmt"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	userv1 "github.com/tinywideclouds/gen-platform/go/types/user/v1"
+	"github.com/tinywideclouds/go-platform/internal/testsupport"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"gopkg.in/yaml.v3"
 )
 
 func TestUser_JSON_RoundTrip(t *testing.T) {
@@ -18,7 +28,7 @@ func TestUser_JSON_RoundTrip(t *testing.T) {
 
 	// REFACTORED: This now expects camelCase, which matches
 	// the test that was failing in the handler.
-	expectedJSON := `{"alias":"Testy","name":"Test McTester","email":"test@example.com"}`
+	expectedJSON := `{"id":null,"alias":"Testy","name":"Test McTester","email":"test@example.com"}`
 
 	// --- Test 1: Marshal (Go struct -> JSON) ---
 	t.Run("MarshalJSON", func(t *testing.T) {
@@ -74,3 +84,533 @@ func TestUser_JSON_RoundTrip(t *testing.T) {
 		assert.Equal(t, nativeStruct, &resultStruct)
 	})
 }
+
+func TestUser_ProfileFields_RoundTrip(t *testing.T) {
+	// Arrange
+	nativeStruct := &User{
+		Alias:     "Testy",
+		Name:      "Test McTester",
+		Email:     "test@example.com",
+		AvatarURL: "https://cdn.example.com/avatars/testy.png",
+		Phone:     "+447700900123",
+		Status:    StatusActive,
+	}
+
+	expectedJSON := `{
+		"id":null,
+		"alias":"Testy",
+		"name":"Test McTester",
+		"email":"test@example.com",
+		"profileUrl":"https://cdn.example.com/avatars/testy.png",
+		"phone":"+447700900123",
+		"status":"active"
+	}`
+
+	// --- Test 1: Proto (AvatarURL only; Phone/Status are not in UserPb) ---
+	t.Run("Proto", func(t *testing.T) {
+		protoPb := ToProto(nativeStruct)
+		assert.Equal(t, nativeStruct.AvatarURL, protoPb.GetProfileUrl())
+
+		roundTrip, err := FromProto(protoPb)
+		require.NoError(t, err)
+		assert.Equal(t, nativeStruct.AvatarURL, roundTrip.AvatarURL)
+		assert.Empty(t, roundTrip.Phone)
+		assert.Empty(t, roundTrip.Status)
+	})
+
+	// --- Test 2: JSON round trip ---
+	t.Run("JSON", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(nativeStruct)
+		require.NoError(t, err)
+		assert.JSONEq(t, expectedJSON, string(jsonBytes))
+
+		var resultStruct User
+		require.NoError(t, json.Unmarshal(jsonBytes, &resultStruct))
+		assert.Equal(t, nativeStruct, &resultStruct)
+	})
+
+	// --- Test 3: Legacy JSON without the new fields ---
+	t.Run("Legacy JSON", func(t *testing.T) {
+		var resultStruct User
+		legacyJSON := `{"alias":"Testy","name":"Test McTester","email":"test@example.com"}`
+		require.NoError(t, json.Unmarshal([]byte(legacyJSON), &resultStruct))
+		assert.Equal(t, User{Alias: "Testy", Name: "Test McTester", Email: "test@example.com"}, resultStruct)
+	})
+
+	// --- Test 4: Empty values are omitted, a zero ID is null ---
+	t.Run("Empty values omitted", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(User{Alias: "Testy"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":null,"alias":"Testy"}`, string(jsonBytes))
+	})
+}
+
+func TestUser_ID_RoundTrip(t *testing.T) {
+	t.Run("With ID", func(t *testing.T) {
+		// Arrange
+		id, err := urn.Parse("urn:sm:user:abc")
+		require.NoError(t, err)
+		nativeStruct := &User{ID: id, Alias: "Testy"}
+
+		// Proto round trip
+		protoPb := ToProto(nativeStruct)
+		assert.Equal(t, "urn:sm:user:abc", protoPb.GetId())
+		roundTrip, err := FromProto(protoPb)
+		require.NoError(t, err)
+		assert.Equal(t, nativeStruct, roundTrip)
+
+		// JSON round trip
+		jsonBytes, err := json.Marshal(nativeStruct)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"urn:sm:user:abc","alias":"Testy"}`, string(jsonBytes))
+
+		var resultStruct User
+		require.NoError(t, json.Unmarshal(jsonBytes, &resultStruct))
+		assert.Equal(t, nativeStruct, &resultStruct)
+	})
+
+	t.Run("Zero ID", func(t *testing.T) {
+		nativeStruct := &User{Alias: "Testy"}
+
+		roundTrip, err := FromProto(ToProto(nativeStruct))
+		require.NoError(t, err)
+		assert.True(t, roundTrip.ID.IsZero())
+
+		jsonBytes, err := json.Marshal(nativeStruct)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":null,"alias":"Testy"}`, string(jsonBytes))
+
+		var resultStruct User
+		require.NoError(t, json.Unmarshal([]byte(`{"id":null,"alias":"Testy"}`), &resultStruct))
+		assert.True(t, resultStruct.ID.IsZero())
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		_, err := FromProto(&userv1.UserPb{Id: "urn:sm:user"})
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+
+		var resultStruct User
+		err = json.Unmarshal([]byte(`{"id":"urn:sm:user"}`), &resultStruct)
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+}
+
+func TestUser_NormalizeEmail(t *testing.T) {
+	u := User{Email: "  Alice@Example.COM "}
+	u.NormalizeEmail()
+	assert.Equal(t, "alice@example.com", u.Email)
+
+	other := User{Email: "alice@example.com"}
+	other.NormalizeEmail()
+	assert.Equal(t, u.Email, other.Email)
+}
+
+func TestUser_ValidateEmail(t *testing.T) {
+	valid := []string{
+		"alice@example.com",
+		"alice.smith+tag@mail.example.co.uk",
+	}
+	for _, email := range valid {
+		assert.NoError(t, User{Email: email}.ValidateEmail(), email)
+	}
+
+	invalid := []string{
+		"",
+		"alice",
+		"alice@",
+		"@example.com",
+		"alice@example",
+		"alice@example.",
+		"alice smith@example.com",
+		"Alice <alice@example.com>",
+		"alice@@example.com",
+	}
+	for _, email := range invalid {
+		assert.ErrorIs(t, User{Email: email}.ValidateEmail(), ErrInvalidEmail, email)
+	}
+}
+
+func TestUserList_RoundTrip(t *testing.T) {
+	// Arrange
+	id, err := urn.Parse("urn:sm:user:abc")
+	require.NoError(t, err)
+
+	nativeList := &UserList{
+		Users: []*User{
+			{ID: id, Alias: "Testy", Name: "Test McTester", Email: "test@example.com", Status: StatusActive},
+			{Alias: "Partial"},
+		},
+	}
+
+	expectedJSON := `{
+		"users": [
+			{"id":"urn:sm:user:abc","alias":"Testy","name":"Test McTester","email":"test@example.com","status":"active"},
+			{"id":null,"alias":"Partial"}
+		]
+	}`
+
+	// --- Test 1: Proto round trip (Status is not carried by UserPb) ---
+	t.Run("Proto", func(t *testing.T) {
+		protoList := ListToProto(nativeList)
+		require.Len(t, protoList, 2)
+		assert.Equal(t, "urn:sm:user:abc", protoList[0].GetId())
+		assert.Equal(t, "Partial", protoList[1].GetAlias())
+
+		roundTrip, err := ListFromProto(protoList)
+		require.NoError(t, err)
+		require.Len(t, roundTrip.Users, 2)
+		assert.Equal(t, id, roundTrip.Users[0].ID)
+		assert.Equal(t, nativeList.Users[1], roundTrip.Users[1])
+	})
+
+	// --- Test 2: JSON round trip ---
+	t.Run("JSON", func(t *testing.T) {
+		jsonBytes, err := json.Marshal(nativeList)
+		require.NoError(t, err)
+		assert.JSONEq(t, expectedJSON, string(jsonBytes))
+
+		var resultList UserList
+		require.NoError(t, json.Unmarshal(jsonBytes, &resultList))
+		assert.Equal(t, nativeList, &resultList)
+	})
+
+	// --- Test 3: Element errors carry the index ---
+	t.Run("Invalid element", func(t *testing.T) {
+		_, err := ListFromProto([]*userv1.UserPb{{Alias: "ok"}, {Id: "urn:sm:user"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "index 1")
+
+		var resultList UserList
+		err = json.Unmarshal([]byte(`{"users":[{"id":"urn:sm:user"}]}`), &resultList)
+		assert.ErrorIs(t, err, urn.ErrInvalidFormat)
+	})
+
+	t.Run("Nil values", func(t *testing.T) {
+		assert.Nil(t, ListToProto(nil))
+		native, err := ListFromProto(nil)
+		require.NoError(t, err)
+		assert.Nil(t, native)
+	})
+}
+
+func TestUser_Merge(t *testing.T) {
+	newStored := func() User {
+		return User{
+			Alias:  "Testy",
+			Name:   "Test McTester",
+			Email:  "test@example.com",
+			Phone:  "+447700900123",
+			Status: StatusActive,
+		}
+	}
+
+	t.Run("Partial patch updates only provided fields", func(t *testing.T) {
+		stored := newStored()
+		stored.Merge(&User{Name: "Tess McTester", Status: StatusSuspended})
+
+		expected := newStored()
+		expected.Name = "Tess McTester"
+		expected.Status = StatusSuspended
+		assert.Equal(t, expected, stored)
+	})
+
+	t.Run("Empty patch changes nothing", func(t *testing.T) {
+		stored := newStored()
+		stored.Merge(&User{})
+		assert.Equal(t, newStored(), stored)
+
+		stored.Merge(nil)
+		assert.Equal(t, newStored(), stored)
+	})
+
+	t.Run("Full patch replaces everything", func(t *testing.T) {
+		id, err := urn.Parse("urn:sm:user:abc")
+		require.NoError(t, err)
+		patch := User{
+			ID:        id,
+			Alias:     "New",
+			Name:      "New Name",
+			Email:     "new@example.com",
+			AvatarURL: "https://cdn.example.com/new.png",
+			Phone:     "+15550100",
+			Status:    StatusSuspended,
+		}
+
+		stored := newStored()
+		stored.Merge(&patch)
+		assert.Equal(t, patch, stored)
+	})
+}
+
+func TestUser_Redacted(t *testing.T) {
+	original := User{
+		Alias:  "Testy",
+		Name:   "Test McTester",
+		Email:  "alice@example.com",
+		Phone:  "+447700900123",
+		Status: StatusActive,
+	}
+
+	t.Run("Masks email and phone", func(t *testing.T) {
+		redacted := original.Redacted()
+
+		assert.Equal(t, "a***@example.com", redacted.Email)
+		assert.Equal(t, "***23", redacted.Phone)
+		assert.Equal(t, original.Alias, redacted.Alias)
+		assert.Equal(t, original.Name, redacted.Name)
+
+		// The original is untouched
+		assert.Equal(t, "alice@example.com", original.Email)
+	})
+
+	t.Run("String never contains raw values", func(t *testing.T) {
+		for _, out := range []string{original.String(), fmt.Sprint(original), fmt.Sprintf("%v", &original)} {
+			assert.NotContains(t, out, "alice@example.com")
+			assert.NotContains(t, out, "+447700900123")
+			assert.Contains(t, out, "a***@example.com")
+			assert.Contains(t, out, "Testy")
+		}
+	})
+
+	t.Run("Edge cases", func(t *testing.T) {
+		assert.Equal(t, User{}, User{}.Redacted())
+		assert.Equal(t, "***", User{Email: "not-an-email"}.Redacted().Email)
+		assert.Equal(t, "é***@example.com", User{Email: "élodie@example.com"}.Redacted().Email)
+		assert.Equal(t, "日***@example.jp", User{Email: "日本@example.jp"}.Redacted().Email)
+		assert.Equal(t, "***", User{Phone: "12"}.Redacted().Phone)
+	})
+}
+
+func TestUser_DisplayName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		user     User
+		expected string
+	}{
+		{"Alias wins", User{Alias: "Testy", Name: "Test McTester", Email: "test@example.com"}, "Testy"},
+		{"Falls back to name", User{Name: "Test McTester", Email: "test@example.com"}, "Test McTester"},
+		{"Falls back to email local part", User{Email: "test@example.com"}, "test"},
+		{"Email without local part", User{Email: "@example.com"}, "@example.com"},
+		{"Empty user", User{}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.user.DisplayName())
+		})
+	}
+}
+
+func TestUser_OpenAPISchema(t *testing.T) {
+	schema, err := User{}.OpenAPISchema()
+	require.NoError(t, err)
+
+	props := schema["properties"].(map[string]any)
+	for _, field := range []string{"id", "alias", "name", "email", "profileUrl", "phone", "status"} {
+		assert.Contains(t, props, field)
+	}
+	assert.Equal(t, map[string]any{"type": "string"}, props["email"])
+}
+
+func TestUser_MarshalJSONWith(t *testing.T) {
+	u := User{Alias: "jd", AvatarURL: "https://example.com/a.png", Phone: "+15550100"}
+
+	data, err := u.MarshalJSONWith(protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true})
+	require.NoError(t, err)
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "jd", m["alias"])
+	assert.Equal(t, "", m["email"], "unpopulated proto fields should be emitted")
+	assert.Equal(t, "https://example.com/a.png", m["profile_url"])
+	assert.NotContains(t, m, "profileUrl")
+	assert.Equal(t, "+15550100", m["phone"], "extension fields are still merged")
+	assert.Equal(t, "", m["status"], "empty extension fields are emitted too")
+	assert.Contains(t, m, "id")
+	assert.Nil(t, m["id"], "a zero ID is null even with EmitUnpopulated")
+
+	var back User
+	require.NoError(t, json.Unmarshal(data, &back))
+	assert.Equal(t, u, back)
+
+	t.Run("Zero ID Multiline", func(t *testing.T) {
+		data, err := u.MarshalJSONWith(protojson.MarshalOptions{Multiline: true})
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), `"id"`))
+		assert.Contains(t, string(data), "\n  \"id\": null")
+		lines := strings.Split(string(data), "\n")
+		require.Len(t, lines, 6, "one member per line")
+		for _, line := range lines[1 : len(lines)-1] {
+			assert.True(t, strings.HasPrefix(line, `  "`), line)
+		}
+		assert.JSONEq(t, `{"id":null,"alias":"jd","profileUrl":"https://example.com/a.png","phone":"+15550100"}`, string(data))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		data, err := UserList{Users: []*User{&u}}.MarshalJSONWith(protojson.MarshalOptions{UseProtoNames: true})
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"profile_url":"https://example.com/a.png"`)
+
+		data, err = UserList{}.MarshalJSONWith(protojson.MarshalOptions{EmitUnpopulated: true})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"users":[]}`, string(data))
+	})
+}
+
+func TestUser_YAML_RoundTrip(t *testing.T) {
+	id, err := urn.New(urn.SecureMessaging, "user", "jd")
+	require.NoError(t, err)
+	original := User{
+		ID:        id,
+		Alias:     "jd",
+		Email:     "jd@example.com",
+		AvatarURL: "https://example.com/a.png",
+		Status:    StatusActive,
+	}
+
+	out, err := yaml.Marshal(original)
+	require.NoError(t, err)
+
+	var got User
+	require.NoError(t, yaml.Unmarshal(out, &got))
+	assert.Equal(t, original, got)
+
+	// Embedded in a config struct, a missing or null user stays zero
+	var cfg struct {
+		Admin User `yaml:"admin"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte("admin: null\n"), &cfg))
+	assert.Equal(t, User{}, cfg.Admin)
+}
+
+func TestApplyFieldMask(t *testing.T) {
+	newStored := func() User {
+		return User{
+			Alias:     "Testy",
+			Name:      "Test McTester",
+			Email:     "test@example.com",
+			AvatarURL: "https://example.com/old.png",
+			Status:    StatusActive,
+		}
+	}
+	src := &User{
+		Alias:     "New Alias",
+		Name:      "",
+		Email:     "new@example.com",
+		AvatarURL: "https://example.com/new.png",
+		Status:    StatusSuspended,
+	}
+
+	t.Run("Single field", func(t *testing.T) {
+		dst := newStored()
+		require.NoError(t, ApplyFieldMask(&dst, src, &fieldmaskpb.FieldMask{Paths: []string{"alias"}}))
+
+		want := newStored()
+		want.Alias = "New Alias"
+		assert.Equal(t, want, dst)
+	})
+
+	t.Run("Multiple fields, including clearing one", func(t *testing.T) {
+		dst := newStored()
+		mask := &fieldmaskpb.FieldMask{Paths: []string{"name", "profile_url", "status"}}
+		require.NoError(t, ApplyFieldMask(&dst, src, mask))
+
+		want := newStored()
+		want.Name = ""
+		want.AvatarURL = "https://example.com/new.png"
+		want.Status = StatusSuspended
+		assert.Equal(t, want, dst)
+	})
+
+	t.Run("Invalid path", func(t *testing.T) {
+		dst := newStored()
+		mask := &fieldmaskpb.FieldMask{Paths: []string{"alias", "profileUrl"}}
+		err := ApplyFieldMask(&dst, src, mask)
+		assert.ErrorIs(t, err, ErrInvalidFieldMask)
+		assert.ErrorContains(t, err, "profileUrl")
+		assert.Equal(t, newStored(), dst, "nothing is applied when a path is invalid")
+	})
+
+	t.Run("Nil mask", func(t *testing.T) {
+		dst := newStored()
+		require.NoError(t, ApplyFieldMask(&dst, src, nil))
+		assert.Equal(t, newStored(), dst)
+	})
+}
+
+func TestUser_JSON_ValueRoundTrip(t *testing.T) {
+	id, err := urn.Parse("urn:sm:user:testy")
+	require.NoError(t, err)
+	u := User{
+		ID:        id,
+		Alias:     "Testy",
+		Name:      "Test McTester",
+		Email:     "test@example.com",
+		AvatarURL: "https://example.com/testy.png",
+		Phone:     "+441234567890",
+		Status:    StatusActive,
+	}
+
+	testsupport.AssertJSONRoundTrip(t, u)
+	testsupport.AssertJSONRoundTrip(t, UserList{Users: []*User{&u, {Alias: "Other"}}})
+}
+
+func TestUser_Diff(t *testing.T) {
+	id, err := urn.Parse("urn:sm:user:testy")
+	require.NoError(t, err)
+	before := User{ID: id, Alias: "Testy", Name: "Test McTester", Email: "test@example.com", Status: StatusActive}
+
+	t.Run("Identical", func(t *testing.T) {
+		assert.Empty(t, before.Diff(before))
+	})
+
+	t.Run("Single field", func(t *testing.T) {
+		after := before
+		after.Email = "new@example.com"
+		assert.Equal(t, map[string][2]string{
+			"email": {"test@example.com", "new@example.com"},
+		}, before.Diff(after))
+	})
+
+	t.Run("Multiple fields", func(t *testing.T) {
+		otherID, err := urn.Parse("urn:sm:user:other")
+		require.NoError(t, err)
+		after := before
+		after.ID = otherID
+		after.Name = ""
+		after.AvatarURL = "https://example.com/a.png"
+		after.Status = StatusSuspended
+		assert.Equal(t, map[string][2]string{
+			"id":         {"urn:sm:user:testy", "urn:sm:user:other"},
+			"name":       {"Test McTester", ""},
+			"profileUrl": {"", "https://example.com/a.png"},
+			"status":     {"active", "suspended"},
+		}, before.Diff(after))
+	})
+}
+
+func TestUser_LogValue(t *testing.T) {
+	id, err := urn.Parse("urn:sm:user:testy")
+	require.NoError(t, err)
+	u := User{ID: id, Alias: "Testy", Email: "testy@example.com", Phone: "+441234567823", Status: StatusActive}
+
+	v := u.LogValue()
+	require.Equal(t, slog.KindGroup, v.Kind())
+	attrs := map[string]string{}
+	for _, a := range v.Group() {
+		attrs[a.Key] = a.Value.String()
+	}
+	assert.Equal(t, map[string]string{
+		"id":     "urn:sm:user:testy",
+		"alias":  "Testy",
+		"email":  "t***@example.com",
+		"phone":  "***23",
+		"status": "active",
+	}, attrs)
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("updated", "user", u)
+	assert.Contains(t, buf.String(), `"user":{"id":"urn:sm:user:testy"`)
+	assert.NotContains(t, buf.String(), "testy@example.com")
+	assert.NotContains(t, buf.String(), "1234567823")
+}
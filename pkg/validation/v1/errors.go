@@ -0,0 +1,54 @@
+// Package validation holds the typed errors the facade packages return for
+// bad input, so HTTP handlers can map them to 400 responses with field
+// context instead of matching on error strings.
+package validation
+
+import (
+	"errors"
+)
+
+// FieldError reports that a single field of the input is invalid. It wraps
+// the package's sentinel (e.g. urn.ErrInvalidFormat) or the underlying parse
+// error, so errors.Is keeps working alongside errors.As.
+type FieldError struct {
+	field  string
+	reason string
+	err    error
+}
+
+// NewFieldError returns a FieldError for field, using its JSON name. err may
+// be nil.
+func NewFieldError(field, reason string, err error) *FieldError {
+	return &FieldError{field: field, reason: reason, err: err}
+}
+
+// Field returns the JSON name of the invalid field.
+func (e *FieldError) Field() string {
+	return e.field
+}
+
+// Reason returns a human-readable description of the problem.
+func (e *FieldError) Reason() string {
+	return e.reason
+}
+
+func (e *FieldError) Error() string {
+	msg := e.field + ": " + e.reason
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.err
+}
+
+// AsFieldError finds the first FieldError in err's chain.
+func AsFieldError(err error) (*FieldError, bool) {
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return fe, true
+	}
+	return nil, false
+}
@@ -0,0 +1,39 @@
+package validation_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinywideclouds/go-platform/pkg/validation/v1"
+)
+
+func TestFieldError(t *testing.T) {
+	sentinel := errors.New("invalid thing")
+
+	t.Run("With a wrapped error", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", validation.NewFieldError("recipientId", "is empty", sentinel))
+
+		fe, ok := validation.AsFieldError(err)
+		require.True(t, ok)
+		assert.Equal(t, "recipientId", fe.Field())
+		assert.Equal(t, "is empty", fe.Reason())
+		assert.ErrorIs(t, err, sentinel)
+		assert.Equal(t, "outer: recipientId: is empty: invalid thing", err.Error())
+	})
+
+	t.Run("Without a wrapped error", func(t *testing.T) {
+		err := validation.NewFieldError("email", "is malformed", nil)
+		assert.Equal(t, "email: is malformed", err.Error())
+		assert.Nil(t, err.Unwrap())
+	})
+
+	t.Run("Not a field error", func(t *testing.T) {
+		fe, ok := validation.AsFieldError(sentinel)
+		assert.False(t, ok)
+		assert.Nil(t, fe)
+	})
+}